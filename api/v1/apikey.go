@@ -0,0 +1,91 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/middleware"
+	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"github.com/wejectchen/ginblog/utils/validator"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// GetApiKeys 查询当前用户的 API 密钥，有用户管理权限时可通过 user_id 查询他人
+func GetApiKeys(c *gin.Context) {
+	userId := c.GetUint("user_id")
+	if id, _ := strconv.Atoi(c.Query("user_id")); id > 0 && uint(id) != userId {
+		if !middleware.HasPermission(c, model.PermUserManage) {
+			code := errmsg.ERROR_FORBIDDEN
+			c.JSON(http.StatusOK, gin.H{
+				"status":  code,
+				"message": errmsg.GetErrMsg(code),
+			})
+			return
+		}
+		userId = uint(id)
+	}
+
+	data, code := model.GetApiKeys(userId)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// AddApiKey 创建 API 密钥，明文密钥只在本次响应中返回
+func AddApiKey(c *gin.Context) {
+	var data struct {
+		Name   string   `json:"name" validate:"required,max=50" label:"名称"`
+		Scopes []string `json:"scopes"`
+		// ExpiresIn 有效天数，0 表示永不过期
+		ExpiresIn int `json:"expires_in" validate:"gte=0" label:"有效天数"`
+	}
+	_ = c.ShouldBindJSON(&data)
+
+	msg, code := validator.Validate(&data)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": msg,
+		})
+		return
+	}
+
+	var expiresAt *time.Time
+	if data.ExpiresIn > 0 {
+		t := time.Now().AddDate(0, 0, data.ExpiresIn)
+		expiresAt = &t
+	}
+	key, apiKey, code := model.CreateApiKey(c.GetUint("user_id"), data.Name, data.Scopes, expiresAt)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    apiKey,
+		"key":     key,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// DeleteApiKey 吊销 API 密钥
+func DeleteApiKey(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	owner, code := model.GetApiKeyOwner(id)
+	if code == errmsg.SUCCESS && !middleware.IsOwnerOrHas(c, owner, model.PermUserManage) {
+		code = errmsg.ERROR_FORBIDDEN
+	}
+	if code == errmsg.SUCCESS {
+		code = model.RevokeApiKey(id)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
@@ -2,18 +2,32 @@ package v1
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/middleware"
 	"github.com/wejectchen/ginblog/model"
 	"github.com/wejectchen/ginblog/utils/errmsg"
+	"github.com/wejectchen/ginblog/utils/markdown"
+	"github.com/wejectchen/ginblog/utils/sanitize"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 // AddArticle 添加文章
 func AddArticle(c *gin.Context) {
 	var data model.Article
 	_ = c.ShouldBindJSON(&data)
+	data.UserId = c.GetUint("user_id")
 
-	code := model.CreateArt(&data)
+	status, code := resolveArtStatus(c, data.Status)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+	data.Status = status
+	code = model.CreateArt(&data, trustedHtml(c))
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  code,
@@ -73,7 +87,7 @@ func GetArt(c *gin.Context) {
 		pageSize = 10
 	}
 
-	if pageNum == 0 {
+	if pageNum < 1 {
 		pageNum = 1
 	}
 	if len(title) == 0 {
@@ -87,7 +101,7 @@ func GetArt(c *gin.Context) {
 		return
 	}
 
-	data, code, total := model.SearchArticle(title, pageSize, pageNum)
+	data, code, total := model.SearchArticle(model.ArtSearch{Keyword: title}, pageSize, pageNum)
 	c.JSON(http.StatusOK, gin.H{
 		"status":  code,
 		"data":    data,
@@ -102,10 +116,124 @@ func EditArt(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 	_ = c.ShouldBindJSON(&data)
 
-	code := model.EditArt(id, &data)
+	if code := checkArtOwner(c, id); code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+
+	status, code := resolveArtStatus(c, data.Status)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+	if status == 0 {
+		status = editedStatus(c, id)
+	}
+
+	code = model.EditArt(id, &data, c.GetUint("user_id"), trustedHtml(c))
+	if code == errmsg.SUCCESS && status != 0 {
+		code = model.SetArtStatus(id, status, data.PublishedAt)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// SetArtStatus 修改文章状态，定时发布需要同时提交 published_at
+func SetArtStatus(c *gin.Context) {
+	var data struct {
+		Status      int        `json:"status"`
+		PublishedAt *time.Time `json:"published_at"`
+	}
+	id, _ := strconv.Atoi(c.Param("id"))
+	_ = c.ShouldBindJSON(&data)
+
+	code := checkArtOwner(c, id)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+	status, code := resolveArtStatus(c, data.Status)
+	if code == errmsg.SUCCESS {
+		if status == 0 {
+			status = model.ArtDraft
+		}
+		code = model.SetArtStatus(id, status, data.PublishedAt)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  code,
+		"data":    status,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// GetArtAdmin 后台查询文章列表，包含草稿等所有状态
+// 没有文章管理权限的作者只能看到自己的文章
+func GetArtAdmin(c *gin.Context) {
+	pageSize, _ := strconv.Atoi(c.Query("pagesize"))
+	pageNum, _ := strconv.Atoi(c.Query("pagenum"))
+	cid, _ := strconv.Atoi(c.Query("cid"))
+	status, _ := strconv.Atoi(c.Query("status"))
+
+	switch {
+	case pageSize >= 100:
+		pageSize = 100
+	case pageSize <= 0:
+		pageSize = 10
+	}
+
+	if pageNum == 0 {
+		pageNum = 1
+	}
+
+	query := model.ArtQuery{
+		Title:  c.Query("title"),
+		Cid:    cid,
+		Status: status,
+	}
+	if !middleware.HasPermission(c, model.PermArticleManage) {
+		query.UserId = c.GetUint("user_id")
+	}
+
+	data, code, total := model.GetArtAdmin(query, pageSize, pageNum)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"total":   total,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// GetArtInfoAdmin 后台查询文章详情，可以查看未发布的文章
+func GetArtInfoAdmin(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	data, code := model.GetArtInfoAdmin(id)
+	if code == errmsg.SUCCESS && data.Status != model.ArtPublished &&
+		!middleware.IsOwnerOrHas(c, data.UserId, model.PermArticleManage) {
+		code = errmsg.ERROR_ART_NOT_EXIST
+	}
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
 		"message": errmsg.GetErrMsg(code),
 	})
 }
@@ -114,6 +242,14 @@ func EditArt(c *gin.Context) {
 func DeleteArt(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 
+	if code := checkArtOwner(c, id); code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+
 	code := model.DeleteArt(id)
 
 	c.JSON(http.StatusOK, gin.H{
@@ -121,3 +257,73 @@ func DeleteArt(c *gin.Context) {
 		"message": errmsg.GetErrMsg(code),
 	})
 }
+
+// PreviewMarkdown 预览 Markdown 渲染结果，不保存
+func PreviewMarkdown(c *gin.Context) {
+	var data struct {
+		Content string `json:"content"`
+	}
+	_ = c.ShouldBindJSON(&data)
+
+	html, err := markdown.Render(data.Content)
+	code := errmsg.SUCCESS
+	if err != nil {
+		code = errmsg.ERROR
+	}
+	// 预览与保存时使用相同的白名单
+	html = sanitize.Article(html, trustedHtml(c))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    html,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// resolveArtStatus 校验提交的文章状态，0 表示不修改
+// 没有发布权限时，发布与定时发布会改为提交审核，归档则直接拒绝
+func resolveArtStatus(c *gin.Context, status int) (int, int) {
+	switch status {
+	case 0, model.ArtDraft, model.ArtPending:
+		return status, errmsg.SUCCESS
+	case model.ArtPublished, model.ArtScheduled:
+		if !middleware.HasPermission(c, model.PermArticlePublish) {
+			return model.ArtPending, errmsg.SUCCESS
+		}
+		return status, errmsg.SUCCESS
+	case model.ArtArchived:
+		if !middleware.HasPermission(c, model.PermArticlePublish) {
+			return status, errmsg.ERROR_FORBIDDEN
+		}
+		return status, errmsg.SUCCESS
+	}
+	return status, errmsg.ERROR_ART_STATUS
+}
+
+// editedStatus 没有发布权限的作者修改已发布的文章后需要重新审核，返回 0 表示状态不变
+func editedStatus(c *gin.Context, id int) int {
+	if middleware.HasPermission(c, model.PermArticlePublish) {
+		return 0
+	}
+	art, _ := model.GetArtInfoAdmin(id)
+	if art.Status == model.ArtPublished || art.Status == model.ArtScheduled {
+		return model.ArtPending
+	}
+	return 0
+}
+
+// checkArtOwner 只有作者本人或拥有文章管理权限的用户可以修改文章
+func checkArtOwner(c *gin.Context, id int) int {
+	author, code := model.GetArtAuthor(id)
+	if code != errmsg.SUCCESS {
+		return code
+	}
+	if !middleware.IsOwnerOrHas(c, author, model.PermArticleManage) {
+		return errmsg.ERROR_FORBIDDEN
+	}
+	return errmsg.SUCCESS
+}
+
+// trustedHtml 拥有 article:html 权限时，文章正文使用更宽松的 HTML 白名单
+func trustedHtml(c *gin.Context) bool {
+	return middleware.HasPermission(c, model.PermArticleHtml)
+}
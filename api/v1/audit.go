@@ -0,0 +1,108 @@
+package v1
+
+import (
+	"encoding/csv"
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// auditExportLimit 单次导出的最大条数
+const auditExportLimit = 10000
+
+// auditQuery 解析筛选条件，日期格式为 2006-01-02，to 包含当天
+func auditQuery(c *gin.Context) model.AuditQuery {
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	q := model.AuditQuery{
+		UserId:     uint(userId),
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetId:   c.Query("target_id"),
+	}
+	if from, err := time.ParseInLocation("2006-01-02", c.Query("from"), time.Local); err == nil {
+		q.From = from
+	}
+	if to, err := time.ParseInLocation("2006-01-02", c.Query("to"), time.Local); err == nil {
+		q.To = to.AddDate(0, 0, 1)
+	}
+	return q
+}
+
+// GetAuditLogs 分页查询审计日志
+func GetAuditLogs(c *gin.Context) {
+	pageSize, _ := strconv.Atoi(c.Query("pagesize"))
+	pageNum, _ := strconv.Atoi(c.Query("pagenum"))
+
+	switch {
+	case pageSize >= 100:
+		pageSize = 100
+	case pageSize <= 0:
+		pageSize = 10
+	}
+
+	if pageNum == 0 {
+		pageNum = 1
+	}
+
+	data, total, code := model.GetAuditLogs(auditQuery(c), pageSize, pageNum)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"total":   total,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// ExportAuditLogs 按筛选条件导出 CSV
+func ExportAuditLogs(c *gin.Context) {
+	data, _, code := model.GetAuditLogs(auditQuery(c), auditExportLimit, 1)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+
+	filename := "audit-" + time.Now().Format("20060102150405") + ".csv"
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	// 写入 BOM，Excel 打开时才能正确识别中文
+	_, _ = c.Writer.WriteString("\xEF\xBB\xBF")
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"ID", "时间", "用户ID", "用户名", "令牌类型", "操作", "路径", "对象类型", "对象ID", "状态码", "变更", "IP", "User-Agent"})
+	for _, log := range data {
+		_ = w.Write(csvRow(
+			strconv.Itoa(int(log.ID)),
+			log.CreatedAt.Format("2006-01-02 15:04:05"),
+			strconv.Itoa(int(log.UserId)),
+			log.Username,
+			log.Scope,
+			log.Action,
+			log.Path,
+			log.TargetType,
+			log.TargetId,
+			strconv.Itoa(log.Status),
+			log.Diff,
+			log.Ip,
+			log.UserAgent,
+		))
+	}
+	w.Flush()
+}
+
+// csvRow 转义以 = + - @ 制表符或回车开头的单元格，前面加上单引号，
+// 防止用户名、路径、User-Agent 等可控内容在 Excel 中被当作公式执行
+func csvRow(cells ...string) []string {
+	for i, cell := range cells {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			cells[i] = "'" + cell
+		}
+	}
+	return cells
+}
@@ -0,0 +1,14 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCsvRow(t *testing.T) {
+	got := csvRow("=HYPERLINK(\"http://evil\")", "+1", "-2+3", "@SUM(A1)", "\tcmd", "\rcmd", "admin", "", "/api/v1/login", "a=b")
+	want := []string{"'=HYPERLINK(\"http://evil\")", "'+1", "'-2+3", "'@SUM(A1)", "'\tcmd", "'\rcmd", "admin", "", "/api/v1/login", "a=b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("csvRow = %q，应为 %q", got, want)
+	}
+}
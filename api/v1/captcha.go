@@ -0,0 +1,52 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/utils/captcha"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"net/http"
+)
+
+// GetCaptcha 获取验证码，类型由配置 [captcha] Type 决定
+// routes 返回需要验证码的接口，前端据此决定是否展示
+func GetCaptcha(c *gin.Context) {
+	data, err := captcha.New("")
+	code := errmsg.SUCCESS
+	if err != nil {
+		code = errmsg.ERROR
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"routes":  captcha.Routes(),
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// VerifyCaptcha 预先校验验证码，通过后返回一次性凭证
+// 凭证与验证码的提交方式相同：请求头 X-Captcha-Id 与 X-Captcha-Answer
+func VerifyCaptcha(c *gin.Context) {
+	var data struct {
+		Id     string `json:"id"`
+		Answer string `json:"answer"`
+	}
+	_ = c.ShouldBindJSON(&data)
+
+	id, answer, ok := captcha.Exchange(data.Id, data.Answer)
+	if !ok {
+		code := errmsg.ERROR_CAPTCHA_WRONG
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": errmsg.SUCCESS,
+		"data": gin.H{
+			"id":     id,
+			"answer": answer,
+		},
+		"message": errmsg.GetErrMsg(errmsg.SUCCESS),
+	})
+}
@@ -88,9 +88,13 @@ func EditCate(c *gin.Context) {
 	var data model.Category
 	id, _ := strconv.Atoi(c.Param("id"))
 	_ = c.ShouldBindJSON(&data)
-	code := model.CheckCategory(data.Name)
+	code := errmsg.SUCCESS
+	// 只修改 slug 时名称不变，无需检查重名
+	if cate, _ := model.GetCateInfo(id); cate.Name != data.Name {
+		code = model.CheckCategory(data.Name)
+	}
 	if code == errmsg.SUCCESS {
-		model.EditCate(id, &data)
+		code = model.EditCate(id, &data)
 	}
 	if code == errmsg.ERROR_CATENAME_USED {
 		c.Abort()
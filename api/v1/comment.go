@@ -2,18 +2,137 @@ package v1
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/middleware"
 	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils"
 	"github.com/wejectchen/ginblog/utils/errmsg"
+	"github.com/wejectchen/ginblog/utils/store"
+	"github.com/wejectchen/ginblog/utils/validator"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
-// AddComment 新增评论
+// commentInput 发表评论的请求参数，游客需填写昵称与邮箱，登录用户的身份取自登录令牌
+type commentInput struct {
+	ArticleId uint   `json:"article_id"`
+	Content   string `json:"content"`
+	Nickname  string `json:"nickname" validate:"required,max=20" label:"昵称"`
+	Email     string `json:"email" validate:"required,email,max=100" label:"邮箱"`
+	Website   string `json:"website" validate:"omitempty,http_url,max=200" label:"网站"`
+}
+
+// bindComment 解析评论参数，审核状态与回复关系由模型层设置
+func bindComment(c *gin.Context) (model.Comment, string, int) {
+	var input commentInput
+	_ = c.ShouldBindJSON(&input)
+	data := model.Comment{
+		ArticleId: input.ArticleId,
+		Content:   input.Content,
+		Ip:        c.ClientIP(),
+	}
+	if id := c.GetUint("user_id"); id > 0 {
+		data.UserId = id
+		data.Username = c.GetString("username")
+		return data, "", errmsg.SUCCESS
+	}
+
+	input.Nickname = strings.TrimSpace(input.Nickname)
+	input.Email = strings.TrimSpace(input.Email)
+	input.Website = strings.TrimSpace(input.Website)
+	msg, code := validator.Validate(&input)
+	if code != errmsg.SUCCESS {
+		return data, msg, code
+	}
+	data.Username = input.Nickname
+	data.Email = input.Email
+	data.Website = input.Website
+	return data, "", errmsg.SUCCESS
+}
+
+// commentThrottled 同一IP在 SpamRateWindow 内最多发表 SpamRateLimit 条评论
+func commentThrottled(ip string) (time.Duration, bool) {
+	if utils.SpamRateLimit <= 0 {
+		return 0, false
+	}
+	key := "comment:rate:" + ip
+	n, _ := store.Default.Incr(key, utils.SpamRateWindow)
+	if n > int64(utils.SpamRateLimit) {
+		wait, _ := store.Default.TTL(key)
+		return wait, true
+	}
+	return 0, false
+}
+
+// publicComment 去掉不对外公开的字段
+func publicComment(data *model.Comment) {
+	data.Email = ""
+	data.Ip = ""
+	data.SpamScore = 0
+	data.SpamReason = ""
+}
+
+// AddComment 新增评论，登录用户的身份取自登录令牌，未登录时作为游客评论
 func AddComment(c *gin.Context) {
-	var data model.Comment
-	_ = c.ShouldBindJSON(&data)
+	data, msg, code := bindComment(c)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": msg,
+		})
+		return
+	}
+	if wait, ok := commentThrottled(data.Ip); ok {
+		code = errmsg.ERROR_COMMENT_THROTTLED
+		c.JSON(http.StatusOK, gin.H{
+			"status":      code,
+			"message":     errmsg.GetErrMsg(code),
+			"retry_after": int(wait.Seconds()) + 1,
+		})
+		return
+	}
+
+	code = model.AddComment(&data)
+	publicComment(&data)
+	// 被判定为垃圾的评论对评论者显示为待审核
+	if data.Status == model.CommentSpam {
+		data.Status = model.CommentPending
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// ReplyComment 回复评论，文章以被回复的评论为准
+func ReplyComment(c *gin.Context) {
+	data, msg, code := bindComment(c)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": msg,
+		})
+		return
+	}
+	if wait, ok := commentThrottled(data.Ip); ok {
+		code = errmsg.ERROR_COMMENT_THROTTLED
+		c.JSON(http.StatusOK, gin.H{
+			"status":      code,
+			"message":     errmsg.GetErrMsg(code),
+			"retry_after": int(wait.Seconds()) + 1,
+		})
+		return
+	}
+	id, _ := strconv.Atoi(c.Param("id"))
 
-	code := model.AddComment(&data)
+	code = model.ReplyComment(id, &data)
+	publicComment(&data)
+	// 被判定为垃圾的评论对评论者显示为待审核
+	if data.Status == model.CommentSpam {
+		data.Status = model.CommentPending
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":  code,
 		"data":    data,
@@ -21,8 +140,45 @@ func AddComment(c *gin.Context) {
 	})
 }
 
-// GetComment 获取单个评论信息
+// GetCommentConfig 前台评论表单的设置
+func GetCommentConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": errmsg.SUCCESS,
+		"data": gin.H{
+			"allow_guest": !model.GetSettingBool(model.SettingCommentGuestDisabled),
+		},
+		"message": errmsg.GetErrMsg(errmsg.SUCCESS),
+	})
+}
+
+// GetComment 前台查询单个评论，只返回已审核通过的评论，已删除的占位不返回作者信息
 func GetComment(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	data, code := model.GetComment(id)
+	if code != errmsg.SUCCESS || data.Status != model.CommentApproved {
+		code = errmsg.ERROR_COMMENT_NOT_EXIST
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+	publicComment(&data)
+	if data.Removed {
+		data.Username = ""
+		data.Content = ""
+		data.Website = ""
+		data.AvatarHash = ""
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// GetCommentAdmin 后台查询单个评论，包括待审核与垃圾评论
+func GetCommentAdmin(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 	data, code := model.GetComment(id)
 	c.JSON(http.StatusOK, gin.H{
@@ -35,7 +191,23 @@ func GetComment(c *gin.Context) {
 // DeleteComment 删除评论
 func DeleteComment(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	code := model.DeleteComment(uint(id))
+	comment, code := model.GetComment(id)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+	if !middleware.IsOwnerOrHas(c, comment.UserId, model.PermCommentModerate) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  errmsg.ERROR_FORBIDDEN,
+			"message": errmsg.GetErrMsg(errmsg.ERROR_FORBIDDEN),
+		})
+		return
+	}
+
+	code = model.DeleteComment(uint(id))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  code,
 		"message": errmsg.GetErrMsg(code),
@@ -131,3 +303,24 @@ func UncheckComment(c *gin.Context) {
 		"message": errmsg.GetErrMsg(code),
 	})
 }
+
+// SpamComment 标记为垃圾评论
+func SpamComment(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	code := model.SpamComment(id)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// RetrainSpam 用人工审核过的评论重新训练垃圾评论分类器
+func RetrainSpam(c *gin.Context) {
+	data, code := model.TrainSpam()
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
@@ -0,0 +1,26 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"github.com/wejectchen/ginblog/utils/keyring"
+	"net/http"
+)
+
+// GetJwks 公开验签公钥，按 JWKS 标准格式返回，供其他服务校验本站令牌
+func GetJwks(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, keyring.Default.JWKS())
+}
+
+// RotateKeys 立即轮换签名密钥，用于怀疑私钥泄露等场景
+func RotateKeys(c *gin.Context) {
+	code := errmsg.SUCCESS
+	if err := keyring.Default.Rotate(); err != nil {
+		code = errmsg.ERROR
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
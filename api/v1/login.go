@@ -5,7 +5,9 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/wejectchen/ginblog/middleware"
 	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils"
 	"github.com/wejectchen/ginblog/utils/errmsg"
+	"github.com/wejectchen/ginblog/utils/lockout"
 	"net/http"
 	"time"
 )
@@ -17,11 +19,21 @@ func Login(c *gin.Context) {
 	var token string
 	var code int
 
+	username := formData.Username
+	if loginLocked(c, username) {
+		return
+	}
 	formData, code = model.CheckLogin(formData.Username, formData.Password)
+	recordLogin(c, username, code)
 
-	if code == errmsg.SUCCESS {
-		setToken(c, formData)
-	} else {
+	switch code {
+	case errmsg.SUCCESS:
+		setToken(c, formData, middleware.ScopeAdmin)
+	case errmsg.ERROR_MFA_REQUIRED:
+		setMfaToken(c, formData, code, mfaPurposeVerify)
+	case errmsg.ERROR_MFA_ENROLL_REQUIRED:
+		setMfaToken(c, formData, code, mfaPurposeEnroll)
+	default:
 		c.JSON(http.StatusOK, gin.H{
 			"status":  code,
 			"data":    formData.Username,
@@ -33,14 +45,23 @@ func Login(c *gin.Context) {
 
 }
 
-// LoginFront 前台登录
+// LoginFront 前台登录，签发只能用于前台接口的读者令牌
 func LoginFront(c *gin.Context) {
 	var formData model.User
 	_ = c.ShouldBindJSON(&formData)
 	var code int
 
+	username := formData.Username
+	if loginLocked(c, username) {
+		return
+	}
 	formData, code = model.CheckLoginFront(formData.Username, formData.Password)
+	recordLogin(c, username, code)
 
+	if code == errmsg.SUCCESS {
+		setToken(c, formData, middleware.ScopeReader)
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":  code,
 		"data":    formData.Username,
@@ -49,34 +70,91 @@ func LoginFront(c *gin.Context) {
 	})
 }
 
-// token生成函数
-func setToken(c *gin.Context, user model.User) {
-	j := middleware.NewJWT()
-	claims := middleware.MyClaims{
-		Username: user.Username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
-			Issuer:    "GinBlog",
-		},
+// loginLocked 账号或IP因多次登录失败处于等待中时直接拒绝
+func loginLocked(c *gin.Context, username string) bool {
+	wait, locked := lockout.Check(c.ClientIP(), username)
+	if !locked {
+		return false
 	}
+	code := errmsg.ERROR_LOGIN_LOCKED
+	c.JSON(http.StatusOK, gin.H{
+		"status":      code,
+		"message":     errmsg.GetErrMsg(code),
+		"retry_after": int(wait.Seconds()) + 1,
+	})
+	return true
+}
 
-	token, err := j.CreateToken(claims)
+// recordLogin 记录登录结果：凭据错误计入失败次数，完整登录成功后清零
+// 等待两步验证的中间状态不清零，避免反复登录绕过验证码的失败计数
+func recordLogin(c *gin.Context, username string, code int) {
+	switch code {
+	case errmsg.ERROR_LOGIN_INVALID, errmsg.ERROR_MFA_CODE_WRONG:
+		lockout.Fail(c.ClientIP(), username)
+	case errmsg.SUCCESS:
+		lockout.Success(username)
+	}
+}
 
+// token生成函数：签发短期访问令牌与可轮换的刷新令牌
+func setToken(c *gin.Context, user model.User, scope string) {
+	c.JSON(http.StatusOK, tokenResponse(c, user, scope))
+}
+
+// tokenResponse 签发令牌、记录登录会话并组装登录成功的响应
+func tokenResponse(c *gin.Context, user model.User, scope string) gin.H {
+	refreshToken, family, code := model.CreateRefreshToken(user.ID, "", scope)
+	if code == errmsg.SUCCESS {
+		code = model.CreateSession(user.ID, family, scope, c.ClientIP(), c.Request.UserAgent())
+	}
+	if code != errmsg.SUCCESS {
+		return gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		}
+	}
+
+	token, err := createAccessToken(user, family, scope)
 	if err != nil {
-		c.JSON(http.StatusOK, gin.H{
+		return gin.H{
 			"status":  errmsg.ERROR,
 			"message": errmsg.GetErrMsg(errmsg.ERROR),
 			"token":   token,
-		})
+		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  200,
-		"data":    user.Username,
-		"id":      user.ID,
-		"message": errmsg.GetErrMsg(200),
-		"token":   token,
-	})
-	return
+	return gin.H{
+		"status":        200,
+		"data":          user.Username,
+		"id":            user.ID,
+		"message":       errmsg.GetErrMsg(200),
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(utils.AccessTokenExpire.Seconds()),
+	}
+}
+
+// createAccessToken 生成访问令牌，读者令牌不携带任何后台权限
+func createAccessToken(user model.User, family string, scope string) (string, error) {
+	j := middleware.NewJWT()
+	now := time.Now()
+	var perms []string
+	if scope == middleware.ScopeAdmin {
+		perms = model.GetRolePermissions(user.Role)
+	}
+	claims := middleware.MyClaims{
+		UserId:      user.ID,
+		Username:    user.Username,
+		Role:        user.Role,
+		Permissions: perms,
+		Scope:       scope,
+		Family:      family,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(utils.AccessTokenExpire)),
+			Issuer:    "GinBlog",
+		},
+	}
+	return j.CreateToken(claims)
 }
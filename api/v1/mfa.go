@@ -0,0 +1,211 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/wejectchen/ginblog/middleware"
+	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 两步验证临时令牌的用途
+const (
+	mfaPurposeVerify = "mfa"
+	mfaPurposeEnroll = "mfa_enroll"
+	mfaTokenExpire   = 5 * time.Minute
+)
+
+type mfaForm struct {
+	MfaToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+	Password string `json:"password"`
+}
+
+// setMfaToken 密码校验通过但还需两步验证时，签发只能用于完成验证的临时令牌
+func setMfaToken(c *gin.Context, user model.User, code int, purpose string) {
+	j := middleware.NewJWT()
+	now := time.Now()
+	token, err := j.CreateToken(middleware.MyClaims{
+		UserId:   user.ID,
+		Username: user.Username,
+		Purpose:  purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(mfaTokenExpire)),
+			Issuer:    "GinBlog",
+		},
+	})
+	if err != nil {
+		code = errmsg.ERROR
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":    code,
+		"data":      user.Username,
+		"id":        user.ID,
+		"message":   errmsg.GetErrMsg(code),
+		"mfa_token": token,
+	})
+}
+
+// parseMfaToken 解析临时令牌并核对用途
+func parseMfaToken(tokenString string, purpose string) (*middleware.MyClaims, int) {
+	claims, err := middleware.NewJWT().ParserToken(tokenString)
+	if err != nil {
+		return nil, errmsg.ERROR_TOKEN_WRONG
+	}
+	if claims.Purpose != purpose {
+		return nil, errmsg.ERROR_TOKEN_TYPE_WRONG
+	}
+	return claims, errmsg.SUCCESS
+}
+
+// LoginMfa 登录第二步：提交验证码（或恢复码）换取正式令牌
+func LoginMfa(c *gin.Context) {
+	var data mfaForm
+	_ = c.ShouldBindJSON(&data)
+
+	claims, code := parseMfaToken(data.MfaToken, mfaPurposeVerify)
+	if code == errmsg.SUCCESS {
+		if loginLocked(c, claims.Username) {
+			return
+		}
+		code = model.VerifyMfa(claims.UserId, data.Code)
+		recordLogin(c, claims.Username, code)
+	}
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+
+	// 临时令牌只能使用一次
+	model.RevokeAccessToken(claims.ID, claims.ExpiresAt.Time)
+	user, _ := model.GetUser(int(claims.UserId))
+	setToken(c, user, middleware.ScopeAdmin)
+}
+
+// LoginMfaSetup 强制开启两步验证时，使用临时令牌生成密钥
+func LoginMfaSetup(c *gin.Context) {
+	var data mfaForm
+	_ = c.ShouldBindJSON(&data)
+
+	claims, code := parseMfaToken(data.MfaToken, mfaPurposeEnroll)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+	totpSetup(c, claims.UserId)
+}
+
+// LoginMfaEnroll 强制开启两步验证时，确认验证码后完成登录
+func LoginMfaEnroll(c *gin.Context) {
+	var data mfaForm
+	_ = c.ShouldBindJSON(&data)
+
+	claims, code := parseMfaToken(data.MfaToken, mfaPurposeEnroll)
+	var codes []string
+	if code == errmsg.SUCCESS {
+		if loginLocked(c, claims.Username) {
+			return
+		}
+		codes, code = model.ConfirmTotp(claims.UserId, data.Code)
+		recordLogin(c, claims.Username, code)
+	}
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+
+	model.RevokeAccessToken(claims.ID, claims.ExpiresAt.Time)
+	user, _ := model.GetUser(int(claims.UserId))
+	res := tokenResponse(c, user, middleware.ScopeAdmin)
+	res["recovery_codes"] = codes
+	c.JSON(http.StatusOK, res)
+}
+
+func totpSetup(c *gin.Context, userId uint) {
+	secret, uri, code := model.SetupTotp(userId)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"secret":  secret,
+		"uri":     uri,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// TotpSetup 为当前用户生成两步验证密钥
+func TotpSetup(c *gin.Context) {
+	totpSetup(c, c.GetUint("user_id"))
+}
+
+// TotpConfirm 确认验证码并开启两步验证，恢复码只返回这一次
+func TotpConfirm(c *gin.Context) {
+	var data mfaForm
+	_ = c.ShouldBindJSON(&data)
+
+	codes, code := model.ConfirmTotp(c.GetUint("user_id"), data.Code)
+	c.JSON(http.StatusOK, gin.H{
+		"status":         code,
+		"recovery_codes": codes,
+		"message":        errmsg.GetErrMsg(code),
+	})
+}
+
+// TotpDisable 关闭两步验证，需要同时提供密码和验证码
+func TotpDisable(c *gin.Context) {
+	var data mfaForm
+	_ = c.ShouldBindJSON(&data)
+	userId := c.GetUint("user_id")
+
+	code := model.CheckPassword(int(userId), data.Password)
+	if code == errmsg.SUCCESS {
+		code = model.VerifyMfa(userId, data.Code)
+	}
+	if code == errmsg.SUCCESS {
+		code = model.DisableTotp(userId)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// TotpRecoveryCodes 重新生成恢复码
+func TotpRecoveryCodes(c *gin.Context) {
+	var data mfaForm
+	_ = c.ShouldBindJSON(&data)
+	userId := c.GetUint("user_id")
+
+	var codes []string
+	code := model.VerifyMfa(userId, data.Code)
+	if code == errmsg.SUCCESS {
+		codes, code = model.RegenerateRecoveryCodes(userId)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":         code,
+		"recovery_codes": codes,
+		"message":        errmsg.GetErrMsg(code),
+	})
+}
+
+// ResetUserTotp 管理员为丢失设备的用户关闭两步验证
+func ResetUserTotp(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	code := model.DisableTotp(uint(id))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
@@ -0,0 +1,179 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/middleware"
+	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"github.com/wejectchen/ginblog/utils/oauth"
+	"github.com/wejectchen/ginblog/utils/store"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const oauthStateExpire = 10 * time.Minute
+
+// oauthState 授权请求的上下文，以 state 为键暂存，回调时取出并作废
+type oauthState struct {
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"`
+	// Nonce 写入授权请求，回调时与 OIDC 的 ID 令牌比对
+	Nonce string `json:"nonce"`
+	// UserId 不为 0 表示已登录用户在绑定第三方账号
+	UserId uint `json:"user_id"`
+}
+
+// GetOAuthProviders 查询已启用的第三方登录方式
+func GetOAuthProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  errmsg.SUCCESS,
+		"data":    oauth.Names(),
+		"message": errmsg.GetErrMsg(errmsg.SUCCESS),
+	})
+}
+
+// OAuthLogin 跳转到第三方授权页面
+func OAuthLogin(c *gin.Context) {
+	authUrl, code := oauthAuthUrl(c, c.Param("provider"), 0)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, authUrl)
+}
+
+// OAuthLink 已登录用户绑定第三方账号，返回授权地址由前端跳转
+func OAuthLink(c *gin.Context) {
+	authUrl, code := oauthAuthUrl(c, c.Param("provider"), c.GetUint("user_id"))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    authUrl,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// oauthAuthUrl 生成带 state、nonce 与 PKCE 挑战的授权地址
+func oauthAuthUrl(c *gin.Context, name string, userId uint) (string, int) {
+	provider, ok := oauth.Get(name)
+	if !ok {
+		return "", errmsg.ERROR_OAUTH_PROVIDER
+	}
+	config, err := provider.Config(oauth.Context(c.Request.Context()))
+	if err != nil {
+		return "", errmsg.ERROR_OAUTH_FAILED
+	}
+
+	state := model.RandomToken(16)
+	verifier := oauth2.GenerateVerifier()
+	nonce := model.RandomToken(16)
+	data, _ := json.Marshal(oauthState{Provider: name, Verifier: verifier, Nonce: nonce, UserId: userId})
+	if err = store.Default.Set("oauth:state:"+state, string(data), oauthStateExpire); err != nil {
+		return "", errmsg.ERROR
+	}
+	return config.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier), oauth2.SetAuthURLParam("nonce", nonce)), errmsg.SUCCESS
+}
+
+// OAuthCallback 第三方授权回调：换取令牌、获取身份，然后登录或绑定
+func OAuthCallback(c *gin.Context) {
+	name := c.Param("provider")
+	provider, ok := oauth.Get(name)
+	if !ok {
+		oauthFinish(c, gin.H{"status": errmsg.ERROR_OAUTH_PROVIDER})
+		return
+	}
+
+	var state oauthState
+	raw, _ := store.Default.Take("oauth:state:" + c.Query("state"))
+	if raw == "" || json.Unmarshal([]byte(raw), &state) != nil || state.Provider != name {
+		oauthFinish(c, gin.H{"status": errmsg.ERROR_OAUTH_STATE})
+		return
+	}
+	if c.Query("error") != "" || c.Query("code") == "" {
+		oauthFinish(c, gin.H{"status": errmsg.ERROR_OAUTH_FAILED})
+		return
+	}
+
+	ctx := oauth.Context(c.Request.Context())
+	config, err := provider.Config(ctx)
+	if err != nil {
+		oauthFinish(c, gin.H{"status": errmsg.ERROR_OAUTH_FAILED})
+		return
+	}
+	token, err := config.Exchange(ctx, c.Query("code"), oauth2.VerifierOption(state.Verifier))
+	if err != nil {
+		oauthFinish(c, gin.H{"status": errmsg.ERROR_OAUTH_FAILED})
+		return
+	}
+	info, err := provider.UserInfo(ctx, token, state.Nonce)
+	if err != nil {
+		oauthFinish(c, gin.H{"status": errmsg.ERROR_OAUTH_FAILED})
+		return
+	}
+
+	identity := &model.UserIdentity{
+		Provider: name,
+		Subject:  info.Subject,
+		Username: info.Username,
+		Email:    info.Email,
+	}
+	if state.UserId != 0 {
+		code := model.LinkIdentity(state.UserId, identity)
+		oauthFinish(c, gin.H{"status": code, "linked": name})
+		return
+	}
+
+	user, code := model.GetIdentityUser(name, info.Subject)
+	if code == errmsg.ERROR_USER_NOT_EXIST {
+		user, code = model.CreateIdentityUser(identity)
+	}
+	if code != errmsg.SUCCESS {
+		oauthFinish(c, gin.H{"status": code})
+		return
+	}
+	oauthFinish(c, tokenResponse(c, user, middleware.ScopeReader))
+}
+
+// oauthFinish 返回回调结果：配置了前台地址时跳转并把结果放在 URL 片段中，否则返回 JSON
+func oauthFinish(c *gin.Context, res gin.H) {
+	if _, ok := res["message"]; !ok {
+		res["message"] = errmsg.GetErrMsg(res["status"].(int))
+	}
+	if utils.OAuthRedirectFront == "" {
+		c.JSON(http.StatusOK, res)
+		return
+	}
+	values := url.Values{}
+	for k, v := range res {
+		values.Set(k, fmt.Sprint(v))
+	}
+	c.Redirect(http.StatusFound, utils.OAuthRedirectFront+"#"+values.Encode())
+}
+
+// GetUserIdentities 查询当前用户绑定的第三方账号
+func GetUserIdentities(c *gin.Context) {
+	data, code := model.GetUserIdentities(c.GetUint("user_id"))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// DeleteUserIdentity 解除绑定第三方账号
+func DeleteUserIdentity(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	code := model.DeleteIdentity(c.GetUint("user_id"), id)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
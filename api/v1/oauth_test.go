@@ -0,0 +1,120 @@
+package v1
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/utils"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"github.com/wejectchen/ginblog/utils/oauth"
+	"github.com/wejectchen/ginblog/utils/oauth/oauthtest"
+	"github.com/wejectchen/ginblog/utils/store"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// oauthRouter 接入模拟提供方作为 oidc，github 只用于校验 state 是否属于当前提供方
+func oauthRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	server := oauthtest.NewServer()
+	t.Cleanup(server.Close)
+	oauth.Register("oidc", utils.OAuthProvider{
+		ClientId:     oauthtest.ClientId,
+		ClientSecret: oauthtest.ClientSecret,
+		RedirectUrl:  "http://localhost:3000/api/v1/oauth/oidc/callback",
+		Issuer:       server.Issuer(),
+	})
+	oauth.Register("github", utils.OAuthProvider{ClientId: "github-client"})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("oauth/:provider/login", OAuthLogin)
+	r.GET("oauth/:provider/callback", OAuthCallback)
+	return r
+}
+
+func oauthGet(r *gin.Engine, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+// oauthLogin 发起登录，返回授权地址中的参数
+func oauthLogin(t *testing.T, r *gin.Engine) url.Values {
+	t.Helper()
+	w := oauthGet(r, "/oauth/oidc/login")
+	if w.Code != http.StatusFound {
+		t.Fatalf("登录应跳转到授权页面，得到 %d %s", w.Code, w.Body.String())
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location.Query()
+}
+
+func oauthStatus(t *testing.T, w *httptest.ResponseRecorder) int {
+	t.Helper()
+	var res struct {
+		Status int `json:"status"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("响应不是 JSON: %s", w.Body.String())
+	}
+	return res.Status
+}
+
+func TestOAuthLoginPKCE(t *testing.T) {
+	r := oauthRouter(t)
+	query := oauthLogin(t, r)
+	if query.Get("code_challenge_method") != "S256" || query.Get("nonce") == "" {
+		t.Fatalf("授权地址缺少 PKCE 挑战或 nonce: %v", query)
+	}
+
+	raw, _ := store.Default.Get("oauth:state:" + query.Get("state"))
+	var state oauthState
+	if err := json.Unmarshal([]byte(raw), &state); err != nil {
+		t.Fatalf("未保存授权请求上下文: %q", raw)
+	}
+	sum := sha256.Sum256([]byte(state.Verifier))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != query.Get("code_challenge") {
+		t.Error("保存的 code_verifier 与授权地址中的挑战不匹配")
+	}
+	if state.Nonce != query.Get("nonce") || state.Provider != "oidc" {
+		t.Errorf("保存的上下文 %+v 与授权地址 %v 不一致", state, query)
+	}
+}
+
+func TestOAuthCallbackState(t *testing.T) {
+	r := oauthRouter(t)
+
+	w := oauthGet(r, "/oauth/oidc/callback?code=abc&state=unknown")
+	if status := oauthStatus(t, w); status != errmsg.ERROR_OAUTH_STATE {
+		t.Errorf("未知的 state: status = %d，应为 %d", status, errmsg.ERROR_OAUTH_STATE)
+	}
+
+	// 为 oidc 签发的 state 不能在其他提供方的回调中使用，使用一次后即作废
+	state := oauthLogin(t, r).Get("state")
+	w = oauthGet(r, "/oauth/github/callback?code=abc&state="+state)
+	if status := oauthStatus(t, w); status != errmsg.ERROR_OAUTH_STATE {
+		t.Errorf("其他提供方的 state: status = %d，应为 %d", status, errmsg.ERROR_OAUTH_STATE)
+	}
+	w = oauthGet(r, "/oauth/oidc/callback?code=abc&state="+state)
+	if status := oauthStatus(t, w); status != errmsg.ERROR_OAUTH_STATE {
+		t.Errorf("已作废的 state: status = %d，应为 %d", status, errmsg.ERROR_OAUTH_STATE)
+	}
+
+	// 用户在提供方拒绝授权时 state 同样作废，不能重放
+	state = oauthLogin(t, r).Get("state")
+	w = oauthGet(r, "/oauth/oidc/callback?error=access_denied&state="+state)
+	if status := oauthStatus(t, w); status != errmsg.ERROR_OAUTH_FAILED {
+		t.Errorf("拒绝授权: status = %d，应为 %d", status, errmsg.ERROR_OAUTH_FAILED)
+	}
+	w = oauthGet(r, "/oauth/oidc/callback?code=abc&state="+state)
+	if status := oauthStatus(t, w); status != errmsg.ERROR_OAUTH_STATE {
+		t.Errorf("重放 state: status = %d，应为 %d", status, errmsg.ERROR_OAUTH_STATE)
+	}
+}
@@ -0,0 +1,63 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"github.com/wejectchen/ginblog/utils/mailer"
+	"github.com/wejectchen/ginblog/utils/validator"
+	"net/http"
+	"net/url"
+)
+
+type resetForm struct {
+	Token    string `json:"token" validate:"required" label:"重置令牌"`
+	Password string `json:"password" validate:"required,min=6,max=120" label:"密码"`
+}
+
+// ForgotPassword 申请找回密码，通过邮件发送重置链接
+// 无论账号是否存在都返回成功，避免被用来探测用户名
+func ForgotPassword(c *gin.Context) {
+	var data struct {
+		Account string `json:"account"`
+	}
+	_ = c.ShouldBindJSON(&data)
+
+	user := model.GetUserByAccount(data.Account)
+	if user.ID > 0 && user.Email != "" {
+		token, code := model.CreatePasswordReset(user.ID)
+		if code == errmsg.SUCCESS {
+			link := utils.SiteUrl + "/resetpassword?token=" + url.QueryEscape(token)
+			mailer.Send(user.Email, "GinBlog 找回密码",
+				"你好 "+user.Username+"：\r\n\r\n请在 "+utils.ResetTokenExpire.String()+" 内打开以下链接重置密码：\r\n"+
+					link+"\r\n\r\n如果不是你本人操作，请忽略本邮件。\r\n")
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  errmsg.SUCCESS,
+		"message": errmsg.GetErrMsg(errmsg.SUCCESS),
+	})
+}
+
+// ResetPassword 使用邮件中的令牌重置密码
+func ResetPassword(c *gin.Context) {
+	var data resetForm
+	_ = c.ShouldBindJSON(&data)
+
+	msg, code := validator.Validate(&data)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": msg,
+		})
+		return
+	}
+
+	code = model.ResetPassword(data.Token, data.Password)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
@@ -2,6 +2,7 @@ package v1
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/middleware"
 	"github.com/wejectchen/ginblog/model"
 	"github.com/wejectchen/ginblog/utils/errmsg"
 	"net/http"
@@ -23,6 +24,14 @@ func UpdateProfile(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 	_ = c.ShouldBindJSON(&data)
 
+	if !middleware.IsOwnerOrHas(c, uint(id), model.PermSettingManage) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  errmsg.ERROR_FORBIDDEN,
+			"message": errmsg.GetErrMsg(errmsg.ERROR_FORBIDDEN),
+		})
+		return
+	}
+
 	code := model.UpdateProfile(id, &data)
 
 	c.JSON(http.StatusOK, gin.H{
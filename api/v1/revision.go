@@ -0,0 +1,119 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"net/http"
+	"strconv"
+)
+
+// GetRevisions 查询文章的修订版本列表
+func GetRevisions(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	pageSize, _ := strconv.Atoi(c.Query("pagesize"))
+	pageNum, _ := strconv.Atoi(c.Query("pagenum"))
+
+	switch {
+	case pageSize >= 100:
+		pageSize = 100
+	case pageSize <= 0:
+		pageSize = 10
+	}
+
+	if pageNum == 0 {
+		pageNum = 1
+	}
+
+	if code := checkArtOwner(c, id); code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+
+	data, total, code := model.GetRevisions(id, pageSize, pageNum)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"total":   total,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// GetRevision 查询单个修订版本的完整内容
+func GetRevision(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	version, _ := strconv.Atoi(c.Param("version"))
+
+	if code := checkArtOwner(c, id); code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+
+	data, code := model.GetRevision(id, version)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// DiffRevisions 比较两个修订版本，to 省略时与最新版本比较
+func DiffRevisions(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	from, _ := strconv.Atoi(c.Query("from"))
+	to, _ := strconv.Atoi(c.Query("to"))
+
+	if code := checkArtOwner(c, id); code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+	if from <= 0 {
+		code := errmsg.ERROR_REVISION_NOT_EXIST
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+
+	data, code := model.DiffRevisions(id, from, to)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// RestoreRevision 将文章恢复为指定版本，恢复后生成新的版本
+func RestoreRevision(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	version, _ := strconv.Atoi(c.Param("version"))
+
+	if code := checkArtOwner(c, id); code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+
+	status := editedStatus(c, id)
+	code := model.RestoreRevision(id, version, c.GetUint("user_id"), trustedHtml(c))
+	if code == errmsg.SUCCESS && status != 0 {
+		code = model.SetArtStatus(id, status, nil)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
@@ -0,0 +1,44 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"net/http"
+	"strconv"
+)
+
+// GetRoles 查询角色列表
+func GetRoles(c *gin.Context) {
+	data, code := model.GetRoles()
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// GetPermissions 查询权限列表
+func GetPermissions(c *gin.Context) {
+	data, code := model.GetPermissions()
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// EditRole 设置角色拥有的权限
+func EditRole(c *gin.Context) {
+	var data struct {
+		Permissions []string `json:"permissions"`
+	}
+	id, _ := strconv.Atoi(c.Param("id"))
+	_ = c.ShouldBindJSON(&data)
+
+	code := model.SetRolePermissions(id, data.Permissions)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
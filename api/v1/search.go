@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchQuery 解析搜索条件，tag 为标签 slug，日期格式为 2006-01-02，to 包含当天
+func searchQuery(c *gin.Context) (model.ArtSearch, int) {
+	cid, _ := strconv.Atoi(c.Query("cid"))
+	q := model.ArtSearch{
+		Keyword: strings.TrimSpace(c.Query("q")),
+		Cid:     cid,
+	}
+	if s := c.Query("tag"); s != "" {
+		tag, code := model.GetTagBySlug(s)
+		if code != errmsg.SUCCESS {
+			return q, code
+		}
+		q.TagId = tag.ID
+	}
+	if from, err := time.ParseInLocation("2006-01-02", c.Query("from"), time.Local); err == nil {
+		q.From = from
+	}
+	if to, err := time.ParseInLocation("2006-01-02", c.Query("to"), time.Local); err == nil {
+		q.To = to.AddDate(0, 0, 1)
+	}
+	return q, errmsg.SUCCESS
+}
+
+// SearchArticle 全文搜索文章，结果按相关度排序，附带高亮的标题与摘要
+func SearchArticle(c *gin.Context) {
+	pageSize, _ := strconv.Atoi(c.Query("pagesize"))
+	pageNum, _ := strconv.Atoi(c.Query("pagenum"))
+
+	switch {
+	case pageSize >= 100:
+		pageSize = 100
+	case pageSize <= 0:
+		pageSize = 10
+	}
+
+	if pageNum < 1 {
+		pageNum = 1
+	}
+
+	q, code := searchQuery(c)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+
+	data, code, total := model.SearchArticle(q, pageSize, pageNum)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"total":   total,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// SuggestSearch 搜索框输入建议，按前缀匹配文章标题与标签，支持全拼与拼音首字母
+func SuggestSearch(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	switch {
+	case limit >= 20:
+		limit = 20
+	case limit <= 0:
+		limit = 10
+	}
+
+	code := errmsg.SUCCESS
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    model.SuggestSearch(c.Query("q"), limit),
+		"message": errmsg.GetErrMsg(code),
+	})
+}
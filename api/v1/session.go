@@ -0,0 +1,83 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/middleware"
+	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"net/http"
+	"strconv"
+)
+
+// GetSessions 查询自己的登录会话，current 标记当前所用的会话
+func GetSessions(c *gin.Context) {
+	claims, _ := middleware.CurrentUser(c)
+	data, code := model.GetSessions(claims.UserId)
+	for i := range data {
+		data[i].Current = data[i].Family == claims.Family
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"total":   len(data),
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// DeleteSession 注销自己的某个会话
+func DeleteSession(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	session, code := model.GetSession(id)
+	if code == errmsg.SUCCESS && session.UserId != c.GetUint("user_id") {
+		code = errmsg.ERROR_FORBIDDEN
+	}
+	if code == errmsg.SUCCESS {
+		code = model.RevokeSession(id)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// DeleteOtherSessions 注销除当前会话外的所有会话
+func DeleteOtherSessions(c *gin.Context) {
+	claims, _ := middleware.CurrentUser(c)
+	code := model.RevokeUserTokens(claims.UserId, claims.Family)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// GetUserSessions 后台查询指定用户的会话
+func GetUserSessions(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	data, code := model.GetSessions(uint(id))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"total":   len(data),
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// AdminDeleteSession 后台注销任意会话
+func AdminDeleteSession(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	code := model.RevokeSession(id)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// DeleteUserSessions 后台注销指定用户的全部会话
+func DeleteUserSessions(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	code := model.RevokeUserTokens(uint(id), "")
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
@@ -0,0 +1,30 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"net/http"
+)
+
+// GetSettings 查询站点设置
+func GetSettings(c *gin.Context) {
+	data, code := model.GetSettings()
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// UpdateSettings 更新站点设置
+func UpdateSettings(c *gin.Context) {
+	var data map[string]string
+	_ = c.ShouldBindJSON(&data)
+
+	code := model.UpdateSettings(data)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
@@ -0,0 +1,104 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// redirectSlug 旧 slug 永久跳转到当前 slug 对应的地址，保留查询参数
+func redirectSlug(c *gin.Context, current string) {
+	location := strings.Replace(c.FullPath(), ":slug", url.PathEscape(current), 1)
+	if c.Request.URL.RawQuery != "" {
+		location += "?" + c.Request.URL.RawQuery
+	}
+	c.Redirect(http.StatusMovedPermanently, location)
+}
+
+// GetArtInfoBySlug 根据 slug 查询文章，旧 slug 返回 301 跳转
+func GetArtInfoBySlug(c *gin.Context) {
+	id, current, redirected, code := model.GetArtIdBySlug(c.Param("slug"))
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+	if redirected {
+		redirectSlug(c, current)
+		return
+	}
+
+	data, code := model.GetArtInfo(int(id))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// GetCateInfoBySlug 根据 slug 查询分类，旧 slug 返回 301 跳转
+func GetCateInfoBySlug(c *gin.Context) {
+	id, current, redirected, code := model.GetCateIdBySlug(c.Param("slug"))
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+	if redirected {
+		redirectSlug(c, current)
+		return
+	}
+
+	data, code := model.GetCateInfo(int(id))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// GetCateArtBySlug 根据分类 slug 查询分类下的文章，旧 slug 返回 301 跳转
+func GetCateArtBySlug(c *gin.Context) {
+	pageSize, _ := strconv.Atoi(c.Query("pagesize"))
+	pageNum, _ := strconv.Atoi(c.Query("pagenum"))
+
+	switch {
+	case pageSize >= 100:
+		pageSize = 100
+	case pageSize <= 0:
+		pageSize = 10
+	}
+
+	if pageNum == 0 {
+		pageNum = 1
+	}
+
+	id, current, redirected, code := model.GetCateIdBySlug(c.Param("slug"))
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+	if redirected {
+		redirectSlug(c, current)
+		return
+	}
+
+	data, code, total := model.GetCateArt(int(id), pageSize, pageNum)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"total":   total,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
@@ -0,0 +1,157 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// AddTag 添加标签
+func AddTag(c *gin.Context) {
+	var data model.Tag
+	_ = c.ShouldBindJSON(&data)
+	code := errmsg.ERROR
+	if strings.TrimSpace(data.Name) != "" {
+		code = model.CheckTag(strings.TrimSpace(data.Name), 0)
+	}
+	if code == errmsg.SUCCESS {
+		code = model.CreateTag(&data)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// GetTags 后台查询标签列表
+func GetTags(c *gin.Context) {
+	pageSize, _ := strconv.Atoi(c.Query("pagesize"))
+	pageNum, _ := strconv.Atoi(c.Query("pagenum"))
+
+	switch {
+	case pageSize >= 100:
+		pageSize = 100
+	case pageSize <= 0:
+		pageSize = 10
+	}
+
+	if pageNum == 0 {
+		pageNum = 1
+	}
+
+	data, total := model.GetTags(c.Query("name"), pageSize, pageNum)
+	code := errmsg.SUCCESS
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"total":   total,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// GetTagCloud 标签云
+func GetTagCloud(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	switch {
+	case limit >= 200:
+		limit = 200
+	case limit <= 0:
+		limit = 50
+	}
+
+	data, code := model.GetTagCloud(limit)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// GetTagArt 查询标签下的文章
+func GetTagArt(c *gin.Context) {
+	pageSize, _ := strconv.Atoi(c.Query("pagesize"))
+	pageNum, _ := strconv.Atoi(c.Query("pagenum"))
+
+	switch {
+	case pageSize >= 100:
+		pageSize = 100
+	case pageSize <= 0:
+		pageSize = 10
+	}
+
+	if pageNum == 0 {
+		pageNum = 1
+	}
+
+	tag, code := model.GetTagBySlug(c.Param("slug"))
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+
+	data, code, total := model.GetTagArt(tag.ID, pageSize, pageNum)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"tag":     tag,
+		"data":    data,
+		"total":   total,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// EditTag 重命名标签
+func EditTag(c *gin.Context) {
+	var data model.Tag
+	id, _ := strconv.Atoi(c.Param("id"))
+	_ = c.ShouldBindJSON(&data)
+
+	_, code := model.GetTagInfo(id)
+	if code == errmsg.SUCCESS {
+		code = errmsg.ERROR
+		if strings.TrimSpace(data.Name) != "" {
+			code = model.CheckTag(strings.TrimSpace(data.Name), id)
+		}
+	}
+	if code == errmsg.SUCCESS {
+		code = model.EditTag(id, &data)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// MergeTags 合并标签
+func MergeTags(c *gin.Context) {
+	var data struct {
+		SourceIds []int `json:"source_ids"`
+		TargetId  int   `json:"target_id"`
+	}
+	_ = c.ShouldBindJSON(&data)
+
+	code := model.MergeTags(data.SourceIds, data.TargetId)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// DeleteTag 删除标签
+func DeleteTag(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	code := model.DeleteTag(id)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
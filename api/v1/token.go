@@ -0,0 +1,87 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/middleware"
+	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"net/http"
+)
+
+type tokenForm struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshToken 使用刷新令牌换取新的访问令牌，旧刷新令牌随即作废
+func RefreshToken(c *gin.Context) {
+	var data tokenForm
+	_ = c.ShouldBindJSON(&data)
+
+	rt, refreshToken, code := model.RotateRefreshToken(data.RefreshToken)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+
+	// 后台令牌刷新时重新检查权限，角色被调整后不能继续使用后台
+	user, _ := model.GetUser(int(rt.UserId))
+	if user.ID == 0 || (rt.Scope == middleware.ScopeAdmin && !model.HasPermission(user.Role, model.PermAdminAccess)) {
+		model.RevokeTokenFamily(rt.Family)
+		code = errmsg.ERROR_USER_NO_RIGHT
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+
+	model.TouchSession(rt.Family, c.ClientIP(), true)
+
+	token, err := createAccessToken(user, rt.Family, rt.Scope)
+	if err != nil {
+		code = errmsg.ERROR
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":        errmsg.SUCCESS,
+		"data":          user.Username,
+		"id":            user.ID,
+		"message":       errmsg.GetErrMsg(errmsg.SUCCESS),
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(utils.AccessTokenExpire.Seconds()),
+	})
+}
+
+// Logout 退出登录：拉黑当前访问令牌并吊销其刷新令牌家族
+func Logout(c *gin.Context) {
+	var data tokenForm
+	_ = c.ShouldBindJSON(&data)
+
+	if tokenString, code := middleware.GetBearerToken(c); code == errmsg.SUCCESS {
+		claims, err := middleware.NewJWT().ParserToken(tokenString)
+		if err == nil {
+			model.RevokeAccessToken(claims.ID, claims.ExpiresAt.Time)
+			if claims.Family != "" {
+				model.RevokeTokenFamily(claims.Family)
+			}
+		}
+	}
+	if data.RefreshToken != "" {
+		model.RevokeRefreshToken(data.RefreshToken)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  errmsg.SUCCESS,
+		"message": errmsg.GetErrMsg(errmsg.SUCCESS),
+	})
+}
@@ -2,21 +2,61 @@ package v1
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/middleware"
 	"github.com/wejectchen/ginblog/model"
 	"github.com/wejectchen/ginblog/utils/errmsg"
+	"github.com/wejectchen/ginblog/utils/lockout"
 	"github.com/wejectchen/ginblog/utils/validator"
 	"net/http"
 	"strconv"
 )
 
-// AddUser 添加用户
+// AddUser 添加用户（前台注册，角色固定为订阅者）
+// 填写邮箱的账号先处于待验证状态，并发送验证邮件
 func AddUser(c *gin.Context) {
 	var data model.User
+	_ = c.ShouldBindJSON(&data)
+	data.Role = model.RoleReader
+	data.Status = model.UserActive
+	data.EmailVerifiedAt = nil
+	if data.Email == "" && model.GetSettingBool(model.SettingRequireEmailVerify) {
+		c.JSON(
+			http.StatusOK, gin.H{
+				"status":  errmsg.ERROR,
+				"message": "邮箱不能为空",
+			},
+		)
+		return
+	}
+	if data.Email != "" {
+		data.Status = model.UserPending
+	}
+	createUser(c, &data)
+}
+
+// AdminAddUser 后台添加用户，可指定角色
+func AdminAddUser(c *gin.Context) {
+	var data model.User
+	_ = c.ShouldBindJSON(&data)
+	data.Status = model.UserActive
+	data.EmailVerifiedAt = nil
+	if code := model.CheckRole(data.Role); code != errmsg.SUCCESS {
+		c.JSON(
+			http.StatusOK, gin.H{
+				"status":  code,
+				"message": errmsg.GetErrMsg(code),
+			},
+		)
+		return
+	}
+	createUser(c, &data)
+}
+
+func createUser(c *gin.Context, data *model.User) {
 	var msg string
 	var validCode int
-	_ = c.ShouldBindJSON(&data)
 
-	msg, validCode = validator.Validate(&data)
+	msg, validCode = validator.Validate(data)
 	if validCode != errmsg.SUCCESS {
 		c.JSON(
 			http.StatusOK, gin.H{
@@ -29,8 +69,14 @@ func AddUser(c *gin.Context) {
 	}
 
 	code := model.CheckUser(data.Username)
+	if code == errmsg.SUCCESS && data.Email != "" && model.GetUserByAccount(data.Email).ID > 0 {
+		code = errmsg.ERROR_EMAIL_USED
+	}
 	if code == errmsg.SUCCESS {
-		model.CreateUser(&data)
+		code = model.CreateUser(data)
+	}
+	if code == errmsg.SUCCESS && data.Status == model.UserPending {
+		sendVerifyEmail(*data)
 	}
 
 	c.JSON(
@@ -95,9 +141,29 @@ func EditUser(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 	_ = c.ShouldBindJSON(&data)
 
+	if !middleware.IsOwnerOrHas(c, uint(id), model.PermUserManage) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  errmsg.ERROR_FORBIDDEN,
+			"message": errmsg.GetErrMsg(errmsg.ERROR_FORBIDDEN),
+		})
+		return
+	}
+	// 只有用户管理员可以分配角色
+	if !middleware.HasPermission(c, model.PermUserManage) {
+		data.Role = 0
+	}
+
 	code := model.CheckUpUser(id, data.Username)
+	if code == errmsg.SUCCESS && data.Email != "" {
+		if other := model.GetUserByAccount(data.Email); other.ID > 0 && other.ID != uint(id) {
+			code = errmsg.ERROR_EMAIL_USED
+		}
+	}
+	if code == errmsg.SUCCESS && data.Role > 0 {
+		code = model.CheckRole(data.Role)
+	}
 	if code == errmsg.SUCCESS {
-		model.EditUser(id, &data)
+		code = model.EditUser(id, &data)
 	}
 
 	c.JSON(
@@ -108,13 +174,60 @@ func EditUser(c *gin.Context) {
 	)
 }
 
+type passwordForm struct {
+	OldPassword string `json:"old_password"`
+	Password    string `json:"password" validate:"required,min=6,max=120" label:"密码"`
+}
+
 // ChangeUserPassword 修改密码
+// 管理员可直接重置他人密码，修改自己的密码时必须提供当前密码
 func ChangeUserPassword(c *gin.Context) {
-	var data model.User
 	id, _ := strconv.Atoi(c.Param("id"))
+
+	if !middleware.IsOwnerOrHas(c, uint(id), model.PermUserManage) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  errmsg.ERROR_FORBIDDEN,
+			"message": errmsg.GetErrMsg(errmsg.ERROR_FORBIDDEN),
+		})
+		return
+	}
+	changePassword(c, id)
+}
+
+// ChangeOwnPassword 修改当前登录用户的密码
+func ChangeOwnPassword(c *gin.Context) {
+	changePassword(c, int(c.GetUint("user_id")))
+}
+
+func changePassword(c *gin.Context, id int) {
+	var data passwordForm
 	_ = c.ShouldBindJSON(&data)
 
-	code := model.ChangePassword(id, &data)
+	msg, code := validator.Validate(&data)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": msg,
+		})
+		return
+	}
+
+	// 修改自己的密码需要验证当前密码，并保留当前这次登录
+	var keepFamily string
+	claims, _ := middleware.CurrentUser(c)
+	if claims != nil && claims.UserId == uint(id) {
+		code = model.CheckPassword(id, data.OldPassword)
+		if code != errmsg.SUCCESS {
+			c.JSON(http.StatusOK, gin.H{
+				"status":  code,
+				"message": errmsg.GetErrMsg(code),
+			})
+			return
+		}
+		keepFamily = claims.Family
+	}
+
+	code = model.ChangePassword(id, data.Password, keepFamily)
 
 	c.JSON(
 		http.StatusOK, gin.H{
@@ -128,7 +241,41 @@ func ChangeUserPassword(c *gin.Context) {
 func DeleteUser(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 
+	if !middleware.IsOwnerOrHas(c, uint(id), model.PermUserManage) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  errmsg.ERROR_FORBIDDEN,
+			"message": errmsg.GetErrMsg(errmsg.ERROR_FORBIDDEN),
+		})
+		return
+	}
+
 	code := model.DeleteUser(id)
+	// 已签发的令牌随之失效，不必等到过期
+	if code == errmsg.SUCCESS {
+		code = model.RevokeUserTokens(uint(id), "")
+	}
+
+	c.JSON(
+		http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		},
+	)
+}
+
+// UnlockUser 解除因登录失败过多导致的账号锁定，可同时指定要解锁的IP
+func UnlockUser(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	user, code := model.GetUser(id)
+	if user.ID == 0 {
+		code = errmsg.ERROR_USER_NOT_EXIST
+	}
+	if code == errmsg.SUCCESS {
+		lockout.Unlock(user.Username)
+		if ip := c.Query("ip"); ip != "" {
+			lockout.UnlockIp(ip)
+		}
+	}
 
 	c.JSON(
 		http.StatusOK, gin.H{
@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"github.com/wejectchen/ginblog/utils/mailer"
+	"github.com/wejectchen/ginblog/utils/store"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sendVerifyEmail 发送邮箱验证链接
+func sendVerifyEmail(user model.User) {
+	token, code := model.CreateEmailVerifyToken(user)
+	if code != errmsg.SUCCESS {
+		return
+	}
+	link := utils.SiteUrl + "/verifyemail?token=" + url.QueryEscape(token)
+	mailer.Send(user.Email, "GinBlog 邮箱验证",
+		"你好 "+user.Username+"：\r\n\r\n感谢注册，请在 "+utils.EmailVerifyExpire.String()+" 内打开以下链接验证邮箱：\r\n"+
+			link+"\r\n\r\n如果不是你本人操作，请忽略本邮件。\r\n")
+}
+
+// VerifyEmail 使用邮件中的令牌验证邮箱
+func VerifyEmail(c *gin.Context) {
+	var data struct {
+		Token string `json:"token"`
+	}
+	_ = c.ShouldBindJSON(&data)
+
+	user, code := model.VerifyEmail(data.Token)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    user.Username,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
+// ResendVerifyEmail 重新发送验证邮件
+// 按输入的账号限制频率，无论账号是否存在都返回相同结果，避免被用来探测用户名
+func ResendVerifyEmail(c *gin.Context) {
+	var data struct {
+		Account string `json:"account"`
+	}
+	_ = c.ShouldBindJSON(&data)
+
+	account := strings.ToLower(strings.TrimSpace(data.Account))
+	if wait, ok := verifyThrottled(account); ok {
+		code := errmsg.ERROR_VERIFY_THROTTLED
+		c.JSON(http.StatusOK, gin.H{
+			"status":      code,
+			"message":     errmsg.GetErrMsg(code),
+			"retry_after": int(wait.Seconds()) + 1,
+		})
+		return
+	}
+
+	user := model.GetUserByAccount(account)
+	if user.ID > 0 && user.Email != "" && user.EmailVerifiedAt == nil {
+		sendVerifyEmail(user)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  errmsg.SUCCESS,
+		"message": errmsg.GetErrMsg(errmsg.SUCCESS),
+	})
+}
+
+// verifyThrottled 同一账号两次发送需间隔 VerifyResendInterval，且每天不超过 VerifyResendDaily 次
+func verifyThrottled(account string) (time.Duration, bool) {
+	intervalKey := "verify:resend:" + account
+	dailyKey := "verify:daily:" + account
+	if wait, _ := store.Default.TTL(intervalKey); wait > 0 {
+		return wait, true
+	}
+	n, _ := store.Default.Incr(dailyKey, 24*time.Hour)
+	if n > int64(utils.VerifyResendDaily) {
+		wait, _ := store.Default.TTL(dailyKey)
+		return wait, true
+	}
+	_ = store.Default.Set(intervalKey, "1", utils.VerifyResendInterval)
+	return 0, false
+}
@@ -8,6 +8,10 @@ import (
 func main() {
 	// 引用数据库
 	model.InitDb()
+	// 定时发布文章
+	go model.RunArticleScheduler()
+	// 清理过期的文章修订版本
+	go model.RunRevisionPruner()
 	// 引入路由组件
 	routes.InitRouter()
 
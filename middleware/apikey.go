@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"net/http"
+)
+
+// apiKeyClaims 校验 API 密钥，并转换为与 JWT 一致的上下文信息
+func apiKeyClaims(key string) (*MyClaims, int) {
+	_, user, perms, code := model.CheckApiKey(key)
+	if code != errmsg.SUCCESS {
+		return nil, code
+	}
+	return &MyClaims{
+		UserId:      user.ID,
+		Username:    user.Username,
+		Role:        user.Role,
+		Permissions: perms,
+		Scope:       ScopeApiKey,
+	}, errmsg.SUCCESS
+}
+
+// LoginOnly 只允许登录令牌访问，挂在修改密码、两步验证、管理密钥等敏感接口上
+// 防止泄露的 API 密钥被用来接管账号或签发新的密钥
+func LoginOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := CurrentUser(c)
+		if !ok || claims.Scope == ScopeApiKey {
+			code := errmsg.ERROR_APIKEY_FORBIDDEN
+			c.JSON(http.StatusOK, gin.H{
+				"status":  code,
+				"message": errmsg.GetErrMsg(code),
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/model"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// auditTargets 路由名与审计目标类型不一致时的映射
+var auditTargets = map[string]string{
+	"changepw":       "user",
+	"checkcomment":   "comment",
+	"uncheckcomment": "comment",
+	"delcomment":     "comment",
+	"settings":       "setting",
+	"apikey":         "api_key",
+	"keys":           "signing_key",
+	"upload":         "file",
+}
+
+// auditSkip 不修改数据的 POST 接口，不记录审计日志
+var auditSkip = map[string]bool{
+	"/api/v1/admin/markdown/preview": true,
+}
+
+// auditWriter 记录响应内容，用于获取业务状态码与新建记录的ID
+type auditWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditWriter) Write(b []byte) (int, error) {
+	if w.body.Len() < 64<<10 {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *auditWriter) WriteString(s string) (int, error) {
+	if w.body.Len() < 64<<10 {
+		w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Audit 审计中间件，挂在后台路由组上，自动记录所有修改类请求
+// 目标类型取自路由路径，如 PUT /api/v1/article/:id 记为 article，ID 取路由参数 id
+func Audit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if auditSkip[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		targetType, targetId := auditTarget(c)
+		id, _ := strconv.Atoi(targetId)
+		before := model.AuditSnapshot(targetType, id)
+
+		w := &auditWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		var resp struct {
+			Status int             `json:"status"`
+			Data   json.RawMessage `json:"data"`
+		}
+		_ = json.Unmarshal(w.body.Bytes(), &resp)
+
+		// 新建类接口没有路由参数，从响应中取新记录的ID
+		if targetId == "" {
+			targetId = responseId(resp.Data)
+			id, _ = strconv.Atoi(targetId)
+		}
+		diff := ""
+		if resp.Status == errmsg.SUCCESS {
+			diff = model.AuditDiff(before, model.AuditSnapshot(targetType, id))
+		}
+
+		data := model.AuditLog{
+			Action:     c.Request.Method + " " + c.FullPath(),
+			Path:       c.Request.URL.Path,
+			TargetType: targetType,
+			TargetId:   targetId,
+			Status:     resp.Status,
+			Diff:       diff,
+			Ip:         c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+		}
+		if claims, ok := CurrentUser(c); ok {
+			data.UserId = claims.UserId
+			data.Username = claims.Username
+			data.Scope = claims.Scope
+		}
+		model.CreateAuditLog(&data)
+	}
+}
+
+// auditTarget 从路由路径推断操作对象，操作自身账号的接口以当前用户为目标
+func auditTarget(c *gin.Context) (string, string) {
+	segments := strings.Split(strings.TrimPrefix(c.FullPath(), "/api/v1/"), "/")
+	self := segments[0] != "admin"
+	if !self && len(segments) > 1 {
+		segments = segments[1:]
+	}
+	targetType := segments[0]
+	if t, ok := auditTargets[targetType]; ok {
+		targetType = t
+	}
+	targetId := c.Param("id")
+	if targetId == "" && targetType == "user" && self {
+		targetId = strconv.Itoa(int(c.GetUint("user_id")))
+	}
+	return targetType, targetId
+}
+
+func responseId(data json.RawMessage) string {
+	var record struct {
+		ID json.Number `json:"ID"`
+		Id json.Number `json:"id"`
+	}
+	if len(data) == 0 || json.Unmarshal(data, &record) != nil {
+		return ""
+	}
+	if record.ID != "" {
+		return record.ID.String()
+	}
+	return record.Id.String()
+}
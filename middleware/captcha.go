@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/utils/captcha"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"net/http"
+)
+
+// 验证码通过请求头提交，不影响各接口自身的请求体解析
+const (
+	CaptchaIdHeader     = "X-Captcha-Id"
+	CaptchaAnswerHeader = "X-Captcha-Answer"
+)
+
+// Captcha 验证码中间件，全局挂载，只对配置 [captcha] Routes 中列出的接口生效
+// 路由格式为 方法:路径，路径与注册路由时一致，如 POST:/api/v1/login
+func Captcha() gin.HandlerFunc {
+	routes := make(map[string]bool)
+	for _, route := range captcha.Routes() {
+		routes[route] = true
+	}
+	return func(c *gin.Context) {
+		if !routes[c.Request.Method+":"+c.FullPath()] {
+			c.Next()
+			return
+		}
+		CaptchaRequired()(c)
+	}
+}
+
+// CaptchaRequired 要求请求携带有效的验证码，可直接挂在单个路由上
+func CaptchaRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !captcha.Verify(c.GetHeader(CaptchaIdHeader), c.GetHeader(CaptchaAnswerHeader)) {
+			code := errmsg.ERROR_CAPTCHA_WRONG
+			c.JSON(http.StatusOK, gin.H{
+				"status":  code,
+				"message": errmsg.GetErrMsg(code),
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -2,27 +2,45 @@ package middleware
 
 import (
 	"errors"
-	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/wejectchen/ginblog/utils"
+	"github.com/wejectchen/ginblog/model"
 	"github.com/wejectchen/ginblog/utils/errmsg"
+	"github.com/wejectchen/ginblog/utils/keyring"
+	"github.com/wejectchen/ginblog/utils/store"
 	"net/http"
 	"strings"
+	"time"
 )
 
 type JWT struct {
-	JwtKey []byte
+	Keys *keyring.Keyring
 }
 
 func NewJWT() *JWT {
 	return &JWT{
-		[]byte(utils.JwtKey),
+		keyring.Default,
 	}
 }
 
+// 令牌作用域：后台管理登录与前台读者登录
+const (
+	ScopeAdmin  = "admin"
+	ScopeReader = "reader"
+	// ScopeApiKey 个人 API 密钥，权限由密钥范围决定
+	ScopeApiKey = "apikey"
+)
+
 type MyClaims struct {
-	Username string `json:"username"`
+	UserId      uint     `json:"uid"`
+	Username    string   `json:"username"`
+	Role        int      `json:"role"`
+	Permissions []string `json:"perms"`
+	Scope       string   `json:"scope"`
+	// Family 刷新令牌家族，家族被吊销时访问令牌一并失效
+	Family string `json:"fid"`
+	// Purpose 非空表示受限用途的临时令牌（如两步验证），不能用于访问接口
+	Purpose string `json:"pur,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -32,61 +50,90 @@ var (
 	TokenNotValidYet = errors.New("token无效,请重新登录。")
 	TokenMalformed   = errors.New("token不正确,请重新登录。")
 	TokenInvalid     = errors.New("这不是一个token,请重新登录。")
+	TokenRevoked     = errors.New("token已失效,请重新登录。")
 )
 
-// CreateToken 生成token
+// CreateToken 生成token，未指定 jti 时自动生成
 func (j *JWT) CreateToken(claims MyClaims) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.JwtKey)
+	if claims.ID == "" {
+		claims.ID = model.RandomToken(16)
+	}
+	key := j.Keys.Signing()
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Alg), claims)
+	if key.Kid != "" {
+		token.Header["kid"] = key.Kid
+	}
+	return token.SignedString(key.SignKey)
 }
 
-// ParserToken 解析token
-func (j *JWT) ParserToken(tokenString string) error {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return j.JwtKey, nil
-	})
+// ParserToken 解析token，并检查是否已被吊销
+// 只接受密钥环中存在的算法，且令牌的算法必须与 kid 对应的密钥一致
+func (j *JWT) ParserToken(tokenString string) (*MyClaims, error) {
+	claims := &MyClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := j.Keys.Lookup(kid)
+		if !ok || token.Method.Alg() != key.Alg {
+			return nil, TokenInvalid
+		}
+		return key.VerifyKey, nil
+	}, jwt.WithValidMethods(j.Keys.Methods()))
 	// 验证token
-	if token.Valid {
-		return nil
+	if err == nil && token.Valid {
+		if model.CheckTokenRevoked(claims.ID, claims.Family) {
+			return nil, TokenRevoked
+		}
+		return claims, nil
 	} else if errors.Is(err, jwt.ErrTokenMalformed) {
-		return TokenMalformed
+		return nil, TokenMalformed
 	} else if errors.Is(err, jwt.ErrTokenExpired) || errors.Is(err, jwt.ErrTokenNotValidYet) {
-		return TokenExpired
+		return nil, TokenExpired
 	} else if errors.Is(err, jwt.ErrTokenSignatureInvalid) {
-		return TokenInvalid
+		return nil, TokenInvalid
 	} else {
-		return TokenNotValidYet
+		return nil, TokenNotValidYet
 	}
 }
 
-// JwtToken jwt中间件
-// todo 优化此类代码
+// GetBearerToken 从请求头中取出 Bearer token
+func GetBearerToken(c *gin.Context) (string, int) {
+	tokenHeader := c.Request.Header.Get("Authorization")
+	if tokenHeader == "" {
+		return "", errmsg.ERROR_TOKEN_EXIST
+	}
+	checkToken := strings.Split(tokenHeader, " ")
+	if len(checkToken) != 2 || checkToken[0] != "Bearer" {
+		return "", errmsg.ERROR_TOKEN_TYPE_WRONG
+	}
+	return checkToken[1], errmsg.SUCCESS
+}
+
+// JwtToken 后台接口的jwt中间件，只接受后台登录签发的令牌或 API 密钥
 func JwtToken() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		var code int
-		tokenHeader := c.Request.Header.Get("Authorization")
-		fmt.Println("请求中带的tokenHeader", tokenHeader)
-		if tokenHeader == "" {
-			code = errmsg.ERROR_TOKEN_EXIST
-			c.JSON(http.StatusOK, gin.H{
-				"status":  code,
-				"message": errmsg.GetErrMsg(code),
-			})
-			c.Abort()
-			return
-		}
+	return jwtAuth(ScopeAdmin, ScopeApiKey)
+}
 
-		checkToken := strings.Split(tokenHeader, " ")
-		if len(checkToken) == 0 {
-			c.JSON(http.StatusOK, gin.H{
-				"status":  code,
-				"message": errmsg.GetErrMsg(code),
-			})
-			c.Abort()
+// JwtReader 前台读者接口的jwt中间件，前后台登录的令牌均可使用
+func JwtReader() gin.HandlerFunc {
+	return jwtAuth(ScopeAdmin, ScopeReader, ScopeApiKey)
+}
+
+// JwtOptional 允许游客访问的接口，携带令牌时按前台读者接口校验，未携带时不设置登录用户
+func JwtOptional() gin.HandlerFunc {
+	reader := JwtReader()
+	return func(c *gin.Context) {
+		if c.Request.Header.Get("Authorization") == "" {
+			c.Next()
 			return
 		}
+		reader(c)
+	}
+}
 
-		if len(checkToken) != 2 || checkToken[0] != "Bearer" {
+func jwtAuth(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, code := GetBearerToken(c)
+		if code != errmsg.SUCCESS {
 			c.JSON(http.StatusOK, gin.H{
 				"status":  code,
 				"message": errmsg.GetErrMsg(code),
@@ -95,30 +142,92 @@ func JwtToken() gin.HandlerFunc {
 			return
 		}
 
-		j := NewJWT()
-		// 解析token
-		err := j.ParserToken(checkToken[1])
-		if err != nil {
-			if errors.Is(err, TokenExpired) {
+		var claims *MyClaims
+		if strings.HasPrefix(tokenString, model.ApiKeyPrefix) {
+			// API 密钥与登录令牌共用同一个请求头
+			claims, code = apiKeyClaims(tokenString)
+			if code != errmsg.SUCCESS {
+				c.JSON(http.StatusOK, gin.H{
+					"status":  code,
+					"message": errmsg.GetErrMsg(code),
+				})
+				c.Abort()
+				return
+			}
+		} else {
+			j := NewJWT()
+			// 解析token
+			parsed, err := j.ParserToken(tokenString)
+			if err != nil {
+				if errors.Is(err, TokenExpired) {
+					c.JSON(http.StatusOK, gin.H{
+						"status":  errmsg.ERROR_TOKEN_RUNTIME,
+						"message": "token授权已过期,请重新登录",
+						"data":    nil,
+					})
+					c.Abort()
+					return
+				}
+				if errors.Is(err, TokenRevoked) {
+					c.JSON(http.StatusOK, gin.H{
+						"status":  errmsg.ERROR_TOKEN_REVOKED,
+						"message": errmsg.GetErrMsg(errmsg.ERROR_TOKEN_REVOKED),
+						"data":    nil,
+					})
+					c.Abort()
+					return
+				}
+				// 其他错误
 				c.JSON(http.StatusOK, gin.H{
 					"status":  errmsg.ERROR,
-					"message": "token授权已过期,请重新登录",
+					"message": err.Error(),
 					"data":    nil,
 				})
 				c.Abort()
 				return
 			}
-			// 其他错误
+			claims = parsed
+		}
+
+		if claims.Purpose != "" || !inScopes(claims.Scope, scopes) {
+			code = errmsg.ERROR_TOKEN_TYPE_WRONG
 			c.JSON(http.StatusOK, gin.H{
-				"status":  errmsg.ERROR,
-				"message": err.Error(),
-				"data":    nil,
+				"status":  code,
+				"message": errmsg.GetErrMsg(code),
 			})
 			c.Abort()
 			return
 		}
 
-		//c.Set("username",)
+		touchSession(c, claims.Family)
+
+		// 将当前登录用户写入上下文，供后续处理函数使用
+		c.Set("claims", claims)
+		c.Set("user_id", claims.UserId)
+		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
 		c.Next()
 	}
 }
+
+// touchSession 记录会话最近活跃时间，每个会话每分钟最多写库一次
+func touchSession(c *gin.Context, family string) {
+	if family == "" {
+		return
+	}
+	key := "session:seen:" + family
+	if seen, _ := store.Default.Get(key); seen != "" {
+		return
+	}
+	_ = store.Default.Set(key, "1", time.Minute)
+	model.TouchSession(family, c.ClientIP(), false)
+}
+
+func inScopes(scope string, scopes []string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
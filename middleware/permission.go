@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"net/http"
+)
+
+// CurrentUser 获取 JwtToken 写入上下文的当前登录用户
+func CurrentUser(c *gin.Context) (*MyClaims, bool) {
+	value, ok := c.Get("claims")
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(*MyClaims)
+	return claims, ok
+}
+
+// HasPermission 判断当前请求的令牌是否携带指定权限
+func HasPermission(c *gin.Context, perm string) bool {
+	claims, ok := CurrentUser(c)
+	if !ok {
+		return false
+	}
+	for _, p := range claims.Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Require 权限校验中间件，需挂在 JwtToken 之后
+func Require(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c, perm) {
+			code := errmsg.ERROR_USER_NO_RIGHT
+			c.JSON(http.StatusOK, gin.H{
+				"status":  code,
+				"message": errmsg.GetErrMsg(code),
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// IsOwnerOrHas 当前用户是资源所有者，或拥有可越权操作的权限
+func IsOwnerOrHas(c *gin.Context, ownerId uint, perm string) bool {
+	claims, ok := CurrentUser(c)
+	if !ok {
+		return false
+	}
+	if ownerId != 0 && claims.UserId == ownerId {
+		return true
+	}
+	return HasPermission(c, perm)
+}
@@ -0,0 +1,145 @@
+package model
+
+import (
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"strings"
+	"time"
+)
+
+// ApiKeyPrefix API 密钥前缀，用于和 JWT 区分
+const ApiKeyPrefix = "gb_"
+
+// ApiKey 个人 API 密钥，供脚本、CI 等非交互场景调用后台接口
+type ApiKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UserId     uint       `gorm:"index;not null" json:"user_id"`
+	Name       string     `gorm:"type:varchar(50);not null" json:"name"`
+	Prefix     string     `gorm:"type:varchar(20)" json:"prefix"`
+	KeyHash    string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	Scopes     string     `gorm:"type:varchar(500)" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Revoked    bool       `gorm:"not null;default:false" json:"revoked"`
+}
+
+// ScopeList 密钥的权限范围
+func (k *ApiKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return nil
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// CreateApiKey 创建 API 密钥，明文只在创建时返回一次
+// scopes 只能是用户角色已拥有的权限
+func CreateApiKey(userId uint, name string, scopes []string, expiresAt *time.Time) (string, ApiKey, int) {
+	var data ApiKey
+	user, _ := GetUser(int(userId))
+	if user.ID == 0 {
+		return "", data, errmsg.ERROR_USER_NOT_EXIST
+	}
+	owned := GetRolePermissions(user.Role)
+	for _, s := range scopes {
+		if !containsString(owned, s) {
+			return "", data, errmsg.ERROR_APIKEY_SCOPE
+		}
+	}
+	if expiresAt != nil && expiresAt.Before(time.Now()) {
+		return "", data, errmsg.ERROR_APIKEY_SCOPE
+	}
+
+	key := ApiKeyPrefix + RandomToken(24)
+	data = ApiKey{
+		UserId:    userId,
+		Name:      name,
+		Prefix:    key[:len(ApiKeyPrefix)+8],
+		KeyHash:   HashToken(key),
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: expiresAt,
+	}
+	/**
+	INSERT INTO api_key (user_id, name, prefix, key_hash, scopes, expires_at, created_at)
+	VALUES (5, 'ci', 'gb_1a2b3c4d', 'sha256', 'article:write,article:publish', NULL, CURRENT_TIMESTAMP);
+	*/
+	err = db.Create(&data).Error
+	if err != nil {
+		return "", data, errmsg.ERROR
+	}
+	return key, data, errmsg.SUCCESS
+}
+
+// GetApiKeys 查询用户的 API 密钥
+func GetApiKeys(userId uint) ([]ApiKey, int) {
+	var keys []ApiKey
+	// SELECT * FROM api_key WHERE user_id = 5 ORDER BY id DESC;
+	err = db.Where("user_id = ?", userId).Order("id desc").Find(&keys).Error
+	if err != nil {
+		return keys, errmsg.ERROR
+	}
+	return keys, errmsg.SUCCESS
+}
+
+// GetApiKeyOwner 查询密钥所属用户
+func GetApiKeyOwner(id int) (uint, int) {
+	var key ApiKey
+	db.Select("user_id").Where("id = ?", id).Limit(1).Find(&key)
+	if key.UserId == 0 {
+		return 0, errmsg.ERROR_APIKEY_INVALID
+	}
+	return key.UserId, errmsg.SUCCESS
+}
+
+// RevokeApiKey 吊销 API 密钥
+func RevokeApiKey(id int) int {
+	// UPDATE api_key SET revoked = true WHERE id = 3;
+	err = db.Model(&ApiKey{}).Where("id = ?", id).Update("revoked", true).Error
+	if err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+// CheckApiKey 校验 API 密钥，返回密钥、所属用户及实际生效的权限
+// 生效权限为密钥范围与用户当前角色权限的交集，角色被降权后密钥随之收紧
+func CheckApiKey(key string) (ApiKey, User, []string, int) {
+	var data ApiKey
+	var user User
+	// SELECT * FROM api_key WHERE key_hash = 'sha256' LIMIT 1;
+	db.Where("key_hash = ?", HashToken(key)).Limit(1).Find(&data)
+	if data.ID == 0 || data.Revoked {
+		return data, user, nil, errmsg.ERROR_APIKEY_INVALID
+	}
+	now := time.Now()
+	if data.ExpiresAt != nil && now.After(*data.ExpiresAt) {
+		return data, user, nil, errmsg.ERROR_APIKEY_INVALID
+	}
+	user, _ = GetUser(int(data.UserId))
+	if user.ID == 0 {
+		return data, user, nil, errmsg.ERROR_APIKEY_INVALID
+	}
+
+	var perms []string
+	owned := GetRolePermissions(user.Role)
+	for _, s := range data.ScopeList() {
+		if containsString(owned, s) {
+			perms = append(perms, s)
+		}
+	}
+
+	// 最近使用时间按分钟记录，避免每个请求都写库
+	if data.LastUsedAt == nil || now.Sub(*data.LastUsedAt) > time.Minute {
+		db.Model(&ApiKey{}).Where("id = ?", data.ID).Update("last_used_at", now)
+		data.LastUsedAt = &now
+	}
+	return data, user, perms, errmsg.SUCCESS
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -2,7 +2,27 @@ package model
 
 import (
 	"github.com/wejectchen/ginblog/utils/errmsg"
+	"github.com/wejectchen/ginblog/utils/markdown"
+	"github.com/wejectchen/ginblog/utils/sanitize"
+	"github.com/wejectchen/ginblog/utils/search"
 	"gorm.io/gorm"
+	"strconv"
+	"time"
+)
+
+// 文章状态
+const (
+	ArtDraft     = 1 // 草稿
+	ArtPending   = 2 // 待审核
+	ArtPublished = 3 // 已发布
+	ArtScheduled = 4 // 定时发布
+	ArtArchived  = 5 // 已归档
+)
+
+// 正文格式
+const (
+	FormatHtml     = "html"
+	FormatMarkdown = "markdown"
 )
 
 // 文章结构体
@@ -10,22 +30,125 @@ import (
 type Article struct {
 	Category Category `gorm:"foreignkey:Cid"`
 	gorm.Model
-	Title        string `gorm:"type:varchar(100);not null" json:"title"`
-	Cid          int    `gorm:"type:int;not null" json:"cid"`
-	Desc         string `gorm:"type:varchar(200)" json:"desc"`
-	Content      string `gorm:"type:longtext" json:"content"`
+	Title   string `gorm:"type:varchar(100);not null" json:"title"`
+	Slug    string `gorm:"type:varchar(100);uniqueIndex" json:"slug"`
+	Cid     int    `gorm:"type:int;not null" json:"cid"`
+	UserId  uint   `gorm:"index" json:"user_id"`
+	Desc    string `gorm:"type:varchar(200)" json:"desc"`
+	Content string `gorm:"type:longtext" json:"content"`
+	// Format 正文格式，Markdown 正文保存时渲染为 HTML 并缓存在 ContentHtml 中
+	Format      string `gorm:"type:varchar(10);not null;default:'html'" json:"format"`
+	ContentHtml string `gorm:"type:longtext" json:"content_html"`
+	// ContentText 去除标签后的正文，用于全文搜索与摘要
+	ContentText  string `gorm:"type:longtext" json:"-"`
 	Img          string `gorm:"type:varchar(100)" json:"img"`
 	CommentCount int    `gorm:"type:int;not null;default:0" json:"comment_count"`
 	ReadCount    int    `gorm:"type:int;not null;default:0" json:"read_count"`
+	// 升级前的文章默认视为已发布
+	Status      int        `gorm:"type:tinyint;not null;default:3;index" json:"status"`
+	PublishedAt *time.Time `gorm:"index" json:"published_at"`
+	Tags        []Tag      `gorm:"many2many:article_tag" json:"tags"`
 }
 
-// CreateArt 新增文章
-func CreateArt(data *Article) int {
+// ArtQuery 后台文章列表的筛选条件，零值表示不筛选
+type ArtQuery struct {
+	Title  string
+	Cid    int
+	Status int
+	UserId uint
+}
+
+// published 前台只能看到已发布的文章
+func published(tx *gorm.DB) *gorm.DB {
+	return tx.Where("article.status = ?", ArtPublished)
+}
+
+// renderContent 返回入库的正文与用于展示的 HTML，两者都经过白名单过滤
+// HTML 格式的正文过滤后直接展示；Markdown 保留原文，只过滤渲染结果
+// trusted 表示作者拥有 article:html 权限，可以使用更宽松的白名单
+func renderContent(format string, content string, trusted bool) (string, string, int) {
+	switch format {
+	case FormatHtml:
+		clean := sanitize.Article(content, trusted)
+		return clean, clean, errmsg.SUCCESS
+	case FormatMarkdown:
+		out, err := markdown.Render(content)
+		if err != nil {
+			return "", "", errmsg.ERROR
+		}
+		return content, sanitize.Article(out, trusted), errmsg.SUCCESS
+	}
+	return "", "", errmsg.ERROR_ART_FORMAT
+}
+
+// initContentHtml 为升级前的文章生成展示用的 HTML
+// 旧文章由后台作者撰写，按受信任的白名单过滤
+func initContentHtml() {
+	var arts []Article
+	// SELECT id, format, content FROM article WHERE content_html IS NULL;
+	db.Unscoped().Select("id, format, content").Where("content_html IS NULL").Find(&arts)
+	for _, art := range arts {
+		if _, out, code := renderContent(art.Format, art.Content, true); code == errmsg.SUCCESS {
+			db.Unscoped().Model(&Article{}).Where("id = ?", art.ID).UpdateColumn("content_html", out)
+		}
+	}
+}
+
+// initContentText 为升级前的文章生成用于搜索的纯文本
+func initContentText() {
+	var arts []Article
+	// SELECT id, content_html FROM article WHERE content_text IS NULL;
+	db.Unscoped().Select("id, content_html").Where("content_text IS NULL").Find(&arts)
+	for _, art := range arts {
+		db.Unscoped().Model(&Article{}).Where("id = ?", art.ID).UpdateColumn("content_text", search.PlainText(art.ContentHtml))
+	}
+}
+
+// initArticleStatus 为升级前已发布的文章补全发布时间
+func initArticleStatus() {
+	// UPDATE article SET published_at = created_at WHERE status = 3 AND published_at IS NULL;
+	db.Model(&Article{}).Where("status = ? AND published_at IS NULL", ArtPublished).
+		UpdateColumn("published_at", gorm.Expr("created_at"))
+}
+
+// CreateArt 新增文章，未指定状态时保存为草稿
+func CreateArt(data *Article, trusted bool) int {
+	if data.Status == 0 {
+		data.Status = ArtDraft
+	}
+	publishedAt, code := publishTime(data.Status, nil, data.PublishedAt)
+	if code != errmsg.SUCCESS {
+		return code
+	}
+	data.PublishedAt = publishedAt
+
+	if data.Format == "" {
+		data.Format = FormatHtml
+	}
+	if data.Content, data.ContentHtml, code = renderContent(data.Format, data.Content, trusted); code != errmsg.SUCCESS {
+		return code
+	}
+	data.ContentText = search.PlainText(data.ContentHtml)
+	data.Slug = uniqueSlug(db, &Article{}, firstNonEmpty(data.Slug, data.Title), "article", 0)
+	// 标签单独保存，避免 GORM 按提交内容直接创建标签
+	tags := data.Tags
+	data.Tags = nil
+
 	err := db.Create(&data).Error
 	if err != nil {
 		return errmsg.ERROR
 	}
-	return errmsg.SUCCESS
+	if data.Status == ArtScheduled {
+		WakeArticleScheduler()
+	}
+	if len(tags) > 0 {
+		if code := SetArtTags(data.ID, tags); code != errmsg.SUCCESS {
+			return code
+		}
+		data.Tags, _ = GetArtTags(data.ID)
+	}
+	indexArticle(data.ID)
+	return saveRevision(data.ID, data.UserId, 0, time.Time{})
 }
 
 // GetCateArt 查询分类下的所有文章
@@ -43,27 +166,99 @@ func GetCateArt(id int, pageSize int, pageNum int) ([]Article, int, int64) {
 	  categories ON articles.cid = categories.id  -- 通过 cid 关联分类表
 	WHERE
 	  articles.cid = 2  -- 筛选分类 ID=2 的文章
+	  AND articles.status = 3  -- 只查询已发布的文章
+	ORDER BY published_at DESC
 	LIMIT 10 OFFSET 0;  -- 取 10 条，跳过 0 条（第 1 页）
 	*/
-	err = db.Preload("Category").Limit(pageSize).Offset((pageNum-1)*pageSize).Where(
-		"cid =?", id).Find(&cateArtList).Error
+	err = db.Scopes(published).Preload("Category").Preload("Tags").Limit(pageSize).Offset((pageNum-1)*pageSize).Where(
+		"cid =?", id).Order("published_at DESC").Find(&cateArtList).Error
 	/**
 	-- 统计分类 ID=2 的文章总数
-	SELECT COUNT(*) FROM articles WHERE cid = 2;
+	SELECT COUNT(*) FROM articles WHERE cid = 2 AND status = 3;
 	*/
-	db.Model(&cateArtList).Where("cid =?", id).Count(&total)
+	db.Model(&cateArtList).Scopes(published).Where("cid =?", id).Count(&total)
 	if err != nil {
 		return nil, errmsg.ERROR_CATE_NOT_EXIST, 0
 	}
 	return cateArtList, errmsg.SUCCESS, total
 }
 
-// GetArtInfo 查询单个文章 查询单篇文章的详细信息，并自动将该文章的阅读量 +1
+// GetTagArt 查询标签下已发布的文章
+func GetTagArt(tagId uint, pageSize int, pageNum int) ([]Article, int, int64) {
+	var articleList []Article
+	var total int64
+	query := func() *gorm.DB {
+		return db.Model(&Article{}).Scopes(published).
+			Joins("JOIN article_tag ON article_tag.article_id = article.id").
+			Where("article_tag.tag_id = ?", tagId)
+	}
+	/**
+	SELECT article.id, title, slug, img, created_at, updated_at, `desc`, comment_count, read_count, status, published_at, category.name
+	FROM article
+	JOIN article_tag ON article_tag.article_id = article.id
+	LEFT JOIN category ON article.cid = category.id
+	WHERE article.status = 3 AND article_tag.tag_id = 3
+	ORDER BY published_at DESC LIMIT 10 OFFSET 0;
+	*/
+	err = query().Select("article.id, title, slug, img, created_at, updated_at, `desc`, comment_count, read_count, status, published_at, Category.name").
+		Joins("Category").Preload("Tags").Order("published_at DESC").
+		Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&articleList).Error
+	query().Count(&total)
+	if err != nil {
+		return nil, errmsg.ERROR, 0
+	}
+	return articleList, errmsg.SUCCESS, total
+}
+
+// GetArtTags 查询文章的标签
+func GetArtTags(id uint) ([]Tag, int) {
+	var tags []Tag
+	/**
+	SELECT tag.* FROM tag JOIN article_tag ON article_tag.tag_id = tag.id
+	WHERE article_tag.article_id = 5;
+	*/
+	err = db.Model(&Article{Model: gorm.Model{ID: id}}).Association("Tags").Find(&tags)
+	if err != nil {
+		return tags, errmsg.ERROR
+	}
+	return tags, errmsg.SUCCESS
+}
+
+// GetArtInfo 查询单个文章 查询单篇已发布文章的详细信息，并自动将该文章的阅读量 +1
 func GetArtInfo(id int) (Article, int) {
 	var art Article
-	err = db.Where("id = ?", id).Preload("Category").First(&art).Error
+	// SELECT * FROM article WHERE id = 5 AND status = 3 LIMIT 1;
+	err = db.Scopes(published).Where("id = ?", id).Preload("Category").Preload("Tags").First(&art).Error
+	if err != nil {
+		return art, errmsg.ERROR_ART_NOT_EXIST
+	}
 
 	db.Model(&art).Where("id = ?", id).UpdateColumn("read_count", gorm.Expr("read_count + ?", 1))
+	return art, errmsg.SUCCESS
+}
+
+// GetArtIdBySlug 根据 slug 查询文章ID，slug 已修改时返回跳转后的ID
+// redirected 为 true 表示 slug 是旧地址，调用方应跳转到文章当前的 slug
+func GetArtIdBySlug(s string) (id uint, current string, redirected bool, code int) {
+	var art Article
+	// SELECT id, slug FROM article WHERE slug = 'hello-world' AND status = 3 LIMIT 1;
+	db.Scopes(published).Select("id, slug").Where("slug = ?", s).Limit(1).Find(&art)
+	if art.ID != 0 {
+		return art.ID, art.Slug, false, errmsg.SUCCESS
+	}
+	if target := FindSlugRedirect(SlugArticle, s); target != 0 {
+		db.Scopes(published).Select("id, slug").Where("id = ?", target).Limit(1).Find(&art)
+		if art.ID != 0 {
+			return art.ID, art.Slug, true, errmsg.SUCCESS
+		}
+	}
+	return 0, "", false, errmsg.ERROR_ART_NOT_EXIST
+}
+
+// GetArtInfoAdmin 后台查询文章详情，不限状态，也不计入阅读量
+func GetArtInfoAdmin(id int) (Article, int) {
+	var art Article
+	err = db.Where("id = ?", id).Preload("Category").Preload("Tags").First(&art).Error
 	if err != nil {
 		return art, errmsg.ERROR_ART_NOT_EXIST
 	}
@@ -90,13 +285,15 @@ func GetArt(pageSize int, pageNum int) ([]Article, int, int64) {
 	  articles
 	INNER JOIN
 	  categories ON articles.cid = categories.id  -- 关联分类表（通过cid）
+	WHERE
+	  articles.status = 3  -- 只查询已发布的文章
 	ORDER BY
-	  created_at DESC  -- 按创建时间倒序（最新的在前）
+	  published_at DESC  -- 按发布时间倒序（最新的在前）
 	LIMIT 10 OFFSET 0;  -- 取10条，跳过0条（第1页）
 	*/
-	err = db.Select("article.id, title, img, created_at, updated_at, `desc`, comment_count, read_count, category.name").Limit(pageSize).Offset((pageNum - 1) * pageSize).Order("Created_At DESC").Joins("Category").Find(&articleList).Error
-	// 单独计数	SELECT COUNT(*) FROM articles;
-	db.Model(&articleList).Count(&total)
+	err = db.Scopes(published).Select("article.id, title, slug, img, created_at, updated_at, `desc`, comment_count, read_count, status, published_at, category.name").Limit(pageSize).Offset((pageNum - 1) * pageSize).Order("published_at DESC").Joins("Category").Preload("Tags").Find(&articleList).Error
+	// 单独计数	SELECT COUNT(*) FROM articles WHERE status = 3;
+	db.Model(&articleList).Scopes(published).Count(&total)
 	if err != nil {
 		return nil, errmsg.ERROR, 0
 	}
@@ -104,54 +301,84 @@ func GetArt(pageSize int, pageNum int) ([]Article, int, int64) {
 
 }
 
-// SearchArticle 搜索文章标题
-func SearchArticle(title string, pageSize int, pageNum int) ([]Article, int, int64) {
+// GetArtAdmin 后台查询文章列表，包含所有状态
+func GetArtAdmin(q ArtQuery, pageSize int, pageNum int) ([]Article, int, int64) {
 	var articleList []Article
-	var err error
 	var total int64
+	query := func() *gorm.DB {
+		tx := db.Model(&Article{})
+		if q.Title != "" {
+			tx = tx.Where("title LIKE ?", q.Title+"%")
+		}
+		if q.Cid > 0 {
+			tx = tx.Where("cid = ?", q.Cid)
+		}
+		if q.Status > 0 {
+			tx = tx.Where("article.status = ?", q.Status)
+		}
+		if q.UserId > 0 {
+			tx = tx.Where("article.user_id = ?", q.UserId)
+		}
+		return tx
+	}
 	/**
-	SELECT
-	  article.id,
-	  title,
-	  img,
-	  created_at,
-	  updated_at,
-	  `desc`,
-	  comment_count,
-	  read_count,
-	  Category.name
-	FROM
-	  articles
-	INNER JOIN
-	  categories ON articles.cid = categories.id  -- 关联分类表，获取分类名称
-	WHERE
-	  title LIKE 'go%'  -- 模糊匹配：标题以"go"开头（如"golang"、"go入门"等）
-	ORDER BY
-	  created_at DESC  -- 按创建时间倒序（最新的在前）
-	LIMIT 10 OFFSET 0;  -- 取10条，跳过0条（第1页）
+	SELECT article.id, title, slug, img, created_at, updated_at, `desc`, comment_count, read_count, status, published_at, user_id, category.name
+	FROM article LEFT JOIN category ON article.cid = category.id
+	WHERE title LIKE 'go%' AND cid = 2 AND article.status = 1 AND article.user_id = 5
+	ORDER BY updated_at DESC LIMIT 10 OFFSET 0;
 	*/
-	err = db.Select("article.id,title, img, created_at, updated_at, `desc`, comment_count, read_count, Category.name").Order("Created_At DESC").Joins("Category").Where("title LIKE ?",
-		title+"%",
-	).Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&articleList).Error
-	//单独计数 SELECT COUNT(*) FROM articles WHERE title LIKE 'go%';  -- 同样的模糊匹配条件
-	db.Model(&articleList).Where("title LIKE ?",
-		title+"%",
-	).Count(&total)
-
+	err = query().Select("article.id, title, slug, img, created_at, updated_at, `desc`, comment_count, read_count, status, published_at, user_id, Category.name").
+		Joins("Category").Preload("Tags").Order("updated_at DESC").Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&articleList).Error
+	query().Count(&total)
 	if err != nil {
 		return nil, errmsg.ERROR, 0
 	}
 	return articleList, errmsg.SUCCESS, total
 }
 
-// EditArt 编辑文章
-func EditArt(id int, data *Article) int {
+// GetArtAuthor 查询文章作者
+func GetArtAuthor(id int) (uint, int) {
+	var art Article
+	// SELECT id, user_id FROM article WHERE id = 5 LIMIT 1;
+	db.Select("id, user_id").Where("id = ?", id).Limit(1).Find(&art)
+	if art.ID == 0 {
+		return 0, errmsg.ERROR_ART_NOT_EXIST
+	}
+	return art.UserId, errmsg.SUCCESS
+}
+
+// EditArt 编辑文章，修改前后的内容都会保存为修订版本
+func EditArt(id int, data *Article, userId uint, trusted bool) int {
+	return editArt(id, data, userId, 0, trusted)
+}
+
+func editArt(id int, data *Article, userId uint, restoredFrom int, trusted bool) int {
 	var art Article
+	ensureBaseRevision(uint(id))
+	db.Select("id, slug, format").Where("id = ?", id).Limit(1).Find(&art)
+
+	// 未提交格式时沿用原格式
+	format := firstNonEmpty(data.Format, art.Format, FormatHtml)
+	content, contentHtml, code := renderContent(format, data.Content, trusted)
+	if code != errmsg.SUCCESS {
+		return code
+	}
+	// 未提交 slug 时保持不变，标题修改后原链接依然有效
+	var newSlug string
+	if data.Slug != "" {
+		newSlug = uniqueSlug(db, &Article{}, data.Slug, "article-"+strconv.Itoa(id), uint(id))
+		if newSlug == art.Slug {
+			newSlug = ""
+		}
+	}
 	var maps = make(map[string]interface{})
 	maps["title"] = data.Title
 	maps["cid"] = data.Cid
 	maps["desc"] = data.Desc
-	maps["content"] = data.Content
+	maps["content"] = content
+	maps["format"] = format
+	maps["content_html"] = contentHtml
+	maps["content_text"] = search.PlainText(contentHtml)
 	maps["img"] = data.Img
 	/**
 	-- 根据 ID 更新文章的指定字段
@@ -161,18 +388,91 @@ func EditArt(id int, data *Article) int {
 	  cid = 3,
 	  `desc` = '新描述',
 	  content = '新内容',
+	  format = 'markdown',
+	  content_html = '渲染后的新内容',
 	  img = 'new-img.png',
+	  slug = 'new-slug',  -- 仅在提交了新的 slug 时更新，同时记录旧 slug 的跳转
 	  updated_at = '当前时间'  -- GORM 自动更新 updated_at 字段
 	WHERE
 	  id = 5;  -- 只更新 ID=5 的文章
 	*/
-	err = db.Model(&art).Where("id = ? ", id).Updates(&maps).Error
+	if newSlug != "" {
+		maps["slug"] = newSlug
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if newSlug != "" {
+			if err := changeSlug(tx, SlugArticle, uint(id), art.Slug, newSlug); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&Article{}).Where("id = ? ", id).Updates(&maps).Error
+	})
 	if err != nil {
 		return errmsg.ERROR
 	}
+	// 未提交 tags 时保持原有标签，提交空数组则清空
+	if data.Tags != nil {
+		if code := SetArtTags(uint(id), data.Tags); code != errmsg.SUCCESS {
+			return code
+		}
+	}
+	indexArticle(uint(id))
+	return saveRevision(uint(id), userId, restoredFrom, time.Time{})
+}
+
+// SetArtStatus 修改文章状态，定时发布使用 publishAt 作为发布时间
+func SetArtStatus(id int, status int, publishAt *time.Time) int {
+	var art Article
+	db.Select("id, status, published_at").Where("id = ?", id).Limit(1).Find(&art)
+	if art.ID == 0 {
+		return errmsg.ERROR_ART_NOT_EXIST
+	}
+	publishedAt, code := publishTime(status, art.PublishedAt, publishAt)
+	if code != errmsg.SUCCESS {
+		return code
+	}
+
+	// UPDATE article SET status = 4, published_at = '2023-01-01 08:00:00' WHERE id = 5;
+	err = db.Model(&Article{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       status,
+		"published_at": publishedAt,
+	}).Error
+	if err != nil {
+		return errmsg.ERROR
+	}
+	if status == ArtScheduled {
+		WakeArticleScheduler()
+	}
+	indexArticle(uint(id))
 	return errmsg.SUCCESS
 }
 
+// publishTime 根据新状态计算发布时间
+// 已发布过的文章保留首次发布时间，撤回定时发布时清空尚未到达的发布时间
+func publishTime(status int, old *time.Time, publishAt *time.Time) (*time.Time, int) {
+	now := time.Now()
+	switch status {
+	case ArtPublished:
+		if old == nil || old.After(now) {
+			return &now, errmsg.SUCCESS
+		}
+		return old, errmsg.SUCCESS
+	case ArtScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			return nil, errmsg.ERROR_ART_SCHEDULE
+		}
+		return publishAt, errmsg.SUCCESS
+	case ArtDraft, ArtPending:
+		if old != nil && old.After(now) {
+			return nil, errmsg.SUCCESS
+		}
+		return old, errmsg.SUCCESS
+	case ArtArchived:
+		return old, errmsg.SUCCESS
+	}
+	return nil, errmsg.ERROR_ART_STATUS
+}
+
 // DeleteArt 删除文章
 func DeleteArt(id int) int {
 	var art Article
@@ -186,5 +486,7 @@ func DeleteArt(id int) int {
 	if err != nil {
 		return errmsg.ERROR
 	}
+	_ = searchEngine.Delete(uint(id))
+	suggester.Remove("article", uint(id))
 	return errmsg.SUCCESS
 }
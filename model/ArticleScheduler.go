@@ -0,0 +1,64 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// artSchedulerWake 新增或修改定时文章后唤醒调度器重新计算等待时间
+var artSchedulerWake = make(chan struct{}, 1)
+
+// artSchedulerInterval 调度器最长等待时间，多实例部署时其他实例设置的定时文章也能及时发布
+const artSchedulerInterval = time.Minute
+
+// WakeArticleScheduler 唤醒定时发布调度器
+func WakeArticleScheduler() {
+	select {
+	case artSchedulerWake <- struct{}{}:
+	default:
+	}
+}
+
+// RunArticleScheduler 定时发布调度器，在发布时间到达时将定时文章改为已发布
+func RunArticleScheduler() {
+	for {
+		wait := artSchedulerInterval
+		if next := PublishDueArticles(); next != nil {
+			if d := time.Until(*next); d < wait {
+				wait = d
+			}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-artSchedulerWake:
+			timer.Stop()
+		}
+	}
+}
+
+// PublishDueArticles 发布已到时间的定时文章，返回下一篇定时文章的发布时间
+func PublishDueArticles() *time.Time {
+	now := time.Now()
+	var due []uint
+	// SELECT id FROM article WHERE status = 4 AND published_at <= CURRENT_TIMESTAMP;
+	db.Model(&Article{}).Where("status = ? AND published_at <= ?", ArtScheduled, now).Pluck("id", &due)
+	if len(due) > 0 {
+		// UPDATE article SET status = 3 WHERE id IN (5, 6) AND status = 4;
+		err = db.Model(&Article{}).Where("id IN ? AND status = ?", due, ArtScheduled).
+			Update("status", ArtPublished).Error
+		if err != nil {
+			fmt.Println("定时发布文章失败：", err)
+		}
+		indexArticles(due)
+	}
+
+	var next Article
+	// SELECT id, published_at FROM article WHERE status = 4 AND published_at > CURRENT_TIMESTAMP ORDER BY published_at LIMIT 1;
+	db.Select("id, published_at").Where("status = ? AND published_at > ?", ArtScheduled, now).
+		Order("published_at").Limit(1).Find(&next)
+	if next.ID == 0 {
+		return nil
+	}
+	return next.PublishedAt
+}
@@ -0,0 +1,207 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"gorm.io/gorm"
+	"reflect"
+	"time"
+)
+
+// AuditLog 后台操作审计日志，只允许追加
+type AuditLog struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+	UserId     uint      `gorm:"index" json:"user_id"`
+	Username   string    `gorm:"type:varchar(20)" json:"username"`
+	Scope      string    `gorm:"type:varchar(20)" json:"scope"`
+	Action     string    `gorm:"type:varchar(100);index" json:"action"`
+	Path       string    `gorm:"type:varchar(255)" json:"path"`
+	TargetType string    `gorm:"type:varchar(30);index:idx_audit_target" json:"target_type"`
+	TargetId   string    `gorm:"type:varchar(64);index:idx_audit_target" json:"target_id"`
+	// Status 接口返回的业务状态码
+	Status int `json:"status"`
+	// Diff 变更字段，格式为 {"字段": [修改前, 修改后]}
+	Diff      string `gorm:"type:text" json:"diff"`
+	Ip        string `gorm:"type:varchar(64)" json:"ip"`
+	UserAgent string `gorm:"type:varchar(500)" json:"user_agent"`
+}
+
+// AuditQuery 审计日志查询条件，零值表示不筛选
+type AuditQuery struct {
+	UserId     uint
+	Action     string
+	TargetType string
+	TargetId   string
+	From       time.Time
+	To         time.Time
+}
+
+var ErrAuditAppendOnly = errors.New("审计日志不允许修改或删除")
+
+// BeforeUpdate 审计日志写入后不可修改
+func (a *AuditLog) BeforeUpdate(_ *gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+// BeforeDelete 审计日志写入后不可删除
+func (a *AuditLog) BeforeDelete(_ *gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+// CreateAuditLog 写入审计日志
+func CreateAuditLog(data *AuditLog) int {
+	if len(data.UserAgent) > 500 {
+		data.UserAgent = data.UserAgent[:500]
+	}
+	/**
+	INSERT INTO audit_log (user_id, username, scope, action, path, target_type, target_id, status, diff, ip, user_agent, created_at)
+	VALUES (1, 'admin', 'admin', 'DELETE /api/v1/article/:id', '/api/v1/article/5', 'article', '5', 200, '{...}', '1.2.3.4', 'Mozilla/5.0 ...', CURRENT_TIMESTAMP);
+	*/
+	err = db.Create(data).Error
+	if err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+func (q AuditQuery) scope(tx *gorm.DB) *gorm.DB {
+	if q.UserId > 0 {
+		tx = tx.Where("user_id = ?", q.UserId)
+	}
+	if q.Action != "" {
+		tx = tx.Where("action LIKE ?", "%"+q.Action+"%")
+	}
+	if q.TargetType != "" {
+		tx = tx.Where("target_type = ?", q.TargetType)
+	}
+	if q.TargetId != "" {
+		tx = tx.Where("target_id = ?", q.TargetId)
+	}
+	if !q.From.IsZero() {
+		tx = tx.Where("created_at >= ?", q.From)
+	}
+	if !q.To.IsZero() {
+		tx = tx.Where("created_at < ?", q.To)
+	}
+	return tx
+}
+
+// GetAuditLogs 分页查询审计日志，按时间倒序
+func GetAuditLogs(q AuditQuery, pageSize int, pageNum int) ([]AuditLog, int64, int) {
+	var logs []AuditLog
+	var total int64
+	/**
+	SELECT * FROM audit_log
+	WHERE user_id = 1 AND target_type = 'article' AND created_at >= '2023-01-01'
+	ORDER BY id DESC LIMIT 10 OFFSET 0;
+	*/
+	q.scope(db.Model(&AuditLog{})).Count(&total)
+	err = q.scope(db).Order("id desc").Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&logs).Error
+	if err != nil {
+		return logs, 0, errmsg.ERROR
+	}
+	return logs, total, errmsg.SUCCESS
+}
+
+// auditSnapshots 按目标类型读取记录快照，用于对比操作前后的差异
+var auditSnapshots = map[string]func(id int) interface{}{
+	"article": func(id int) interface{} {
+		var data Article
+		db.Where("id = ?", id).Limit(1).Find(&data)
+		return data
+	},
+	"category": func(id int) interface{} {
+		data, _ := GetCateInfo(id)
+		return data
+	},
+	"user": func(id int) interface{} {
+		data, _ := GetUser(id)
+		return data
+	},
+	"tag": func(id int) interface{} {
+		data, _ := GetTagInfo(id)
+		return data
+	},
+	"comment": func(id int) interface{} {
+		var data Comment
+		db.Where("id = ?", id).Limit(1).Find(&data)
+		return data
+	},
+	"profile": func(id int) interface{} {
+		data, _ := GetProfile(id)
+		return data
+	},
+	"role": func(id int) interface{} {
+		var data Role
+		db.Preload("Permissions").Where("id = ?", id).Limit(1).Find(&data)
+		return data
+	},
+	"setting": func(_ int) interface{} {
+		data, _ := GetSettings()
+		return data
+	},
+}
+
+// auditRedact 不写入审计日志明文的字段，只保留摘要用于判断是否修改过
+var auditRedact = map[string]bool{
+	"password": true,
+}
+
+// auditMaxText 超长文本（如文章正文）在日志中截断
+const auditMaxText = 500
+
+// AuditSnapshot 读取目标记录当前状态，不支持的类型或记录不存在时返回 nil
+func AuditSnapshot(targetType string, id int) map[string]interface{} {
+	load, ok := auditSnapshots[targetType]
+	if !ok {
+		return nil
+	}
+	data := load(id)
+	if v := reflect.ValueOf(data); v.Kind() == reflect.Struct && v.FieldByName("ID").IsValid() && v.FieldByName("ID").IsZero() {
+		return nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	_ = json.Unmarshal(raw, &fields)
+	for k, v := range fields {
+		if auditRedact[k] {
+			fields[k] = "******" + HashToken(fmt.Sprint(v))[:6]
+			continue
+		}
+		if text, ok := v.(string); ok && len([]rune(text)) > auditMaxText {
+			// 追加摘要，截断部分之后的修改同样能体现在差异中
+			fields[k] = string([]rune(text)[:auditMaxText]) + "…#" + HashToken(text)[:6]
+		}
+	}
+	return fields
+}
+
+// AuditDiff 对比前后快照，只保留发生变化的字段
+func AuditDiff(before map[string]interface{}, after map[string]interface{}) string {
+	if before == nil && after == nil {
+		return ""
+	}
+	diff := make(map[string][2]interface{})
+	for k, v := range before {
+		if !reflect.DeepEqual(v, after[k]) {
+			diff[k] = [2]interface{}{v, after[k]}
+		}
+	}
+	for k, v := range after {
+		if _, ok := before[k]; !ok {
+			diff[k] = [2]interface{}{nil, v}
+		}
+	}
+	delete(diff, "UpdatedAt")
+	if len(diff) == 0 {
+		return ""
+	}
+	raw, _ := json.Marshal(diff)
+	return string(raw)
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"github.com/wejectchen/ginblog/utils/errmsg"
 	"gorm.io/gorm"
+	"strconv"
 )
 
 //文章分类的结构表
@@ -10,6 +11,7 @@ import (
 type Category struct {
 	ID   uint   `gorm:"primary_key;auto_increment" json:"id"`
 	Name string `gorm:"type:varchar(20);not null" json:"name"`
+	Slug string `gorm:"type:varchar(100);uniqueIndex" json:"slug"`
 }
 
 // CheckCategory 查询分类是否存在
@@ -26,9 +28,10 @@ func CheckCategory(name string) (code int) {
 
 // CreateCate 新增分类
 func CreateCate(data *Category) int {
+	data.Slug = uniqueSlug(db, &Category{}, firstNonEmpty(data.Slug, data.Name), "category", 0)
 	/**-- 向分类表插入一条新记录
-	INSERT INTO categories (name, created_at, updated_at)
-	VALUES ('后端开发', '当前时间戳', '当前时间戳');
+	INSERT INTO categories (name, slug, created_at, updated_at)
+	VALUES ('后端开发', 'hou-duan-kai-fa', '当前时间戳', '当前时间戳');
 	*/
 	err := db.Create(&data).Error
 	if err != nil {
@@ -67,18 +70,48 @@ func GetCate(pageSize int, pageNum int) ([]Category, int64) {
 	return cate, total
 }
 
-// EditCate 编辑分类信息
+// GetCateIdBySlug 根据 slug 查询分类ID，redirected 为 true 表示 slug 是修改前的旧地址
+func GetCateIdBySlug(s string) (id uint, current string, redirected bool, code int) {
+	var cate Category
+	// SELECT id, slug FROM category WHERE slug = 'hou-duan' LIMIT 1;
+	db.Select("id, slug").Where("slug = ?", s).Limit(1).Find(&cate)
+	if cate.ID != 0 {
+		return cate.ID, cate.Slug, false, errmsg.SUCCESS
+	}
+	if target := FindSlugRedirect(SlugCategory, s); target != 0 {
+		db.Select("id, slug").Where("id = ?", target).Limit(1).Find(&cate)
+		if cate.ID != 0 {
+			return cate.ID, cate.Slug, true, errmsg.SUCCESS
+		}
+	}
+	return 0, "", false, errmsg.ERROR_CATE_NOT_EXIST
+}
+
+// EditCate 编辑分类信息，提交新的 slug 时记录旧 slug 的跳转
 func EditCate(id int, data *Category) int {
 	var cate Category
 	var maps = make(map[string]interface{})
 	maps["name"] = data.Name
+	if data.Slug != "" {
+		db.Select("id, slug").Where("id = ?", id).Limit(1).Find(&cate)
+		if s := uniqueSlug(db, &Category{}, data.Slug, "category-"+strconv.Itoa(id), uint(id)); s != cate.Slug {
+			maps["slug"] = s
+		}
+	}
 	/**
 	-- 假设 id=5，要更新的分类名称为 "新分类名"
 	UPDATE categories
-	SET name = '新分类名', updated_at = CURRENT_TIMESTAMP  -- GORM 自动更新更新时间
+	SET name = '新分类名', slug = 'xin-fen-lei', updated_at = CURRENT_TIMESTAMP  -- GORM 自动更新更新时间
 	WHERE id = 5;
 	*/
-	err = db.Model(&cate).Where("id = ? ", id).Updates(maps).Error
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if s, ok := maps["slug"].(string); ok {
+			if err := changeSlug(tx, SlugCategory, uint(id), cate.Slug, s); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&Category{}).Where("id = ? ", id).Updates(maps).Error
+	})
 	if err != nil {
 		return errmsg.ERROR
 	}
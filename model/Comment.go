@@ -1,8 +1,14 @@
 package model
 
 import (
+	"crypto/md5"
+	"encoding/hex"
+	"github.com/wejectchen/ginblog/utils"
 	"github.com/wejectchen/ginblog/utils/errmsg"
+	"github.com/wejectchen/ginblog/utils/sanitize"
 	"gorm.io/gorm"
+	"strings"
+	"unicode/utf8"
 )
 
 // Comment 评论结构体
@@ -14,10 +20,137 @@ type Comment struct {
 	Username  string `gorm:"type:varchar(500);not null;" json:"username"`
 	Content   string `gorm:"type:varchar(500);not null;" json:"content"`
 	Status    int8   `gorm:"type:tinyint;default:2" json:"status"`
+	// 游客评论（UserId 为 0）时 Username 为游客填写的昵称，Email 只在后台展示
+	Email   string `gorm:"type:varchar(100);index" json:"email,omitempty"`
+	Website string `gorm:"type:varchar(200)" json:"website"`
+	// AvatarHash 邮箱的 MD5 值，用于生成 Gravatar 头像，Avatar 为查询时拼接的头像地址
+	AvatarHash string `gorm:"type:varchar(32)" json:"-"`
+	Avatar     string `gorm:"-" json:"avatar"`
+	// Ip 评论者的IP，用于判断重复内容与后台排查
+	Ip string `gorm:"type:varchar(45)" json:"ip,omitempty"`
+	// SpamScore 垃圾评论评分（0-1），SpamReason 评分依据
+	SpamScore  float64 `gorm:"not null;default:0" json:"spam_score"`
+	SpamReason string  `gorm:"type:varchar(200)" json:"spam_reason"`
+	// Reviewed 审核状态由管理员人工确认，只有人工审核的评论参与垃圾评论分类器的训练
+	Reviewed bool `gorm:"not null;default:false" json:"reviewed"`
+	// ParentId 回复的评论，顶层评论为 0；RootId 所在讨论串的顶层评论，顶层评论自身为 0
+	ParentId uint `gorm:"index" json:"parent_id"`
+	RootId   uint `gorm:"index" json:"root_id"`
+	// Depth 回复层级，顶层评论为 0
+	Depth int `gorm:"type:tinyint;not null;default:0" json:"depth"`
+	// ReplyTo 被回复的用户名，超过层级上限的回复挂到上一层时用于保留上下文
+	ReplyTo string `gorm:"type:varchar(500)" json:"reply_to"`
+	// Removed 有回复的评论删除后保留为占位，内容清空
+	Removed bool `gorm:"not null;default:false" json:"removed"`
+	// ReplyCount 讨论串中该评论下的回复总数，Replies 为直接回复，查询时生成
+	ReplyCount int        `gorm:"-" json:"reply_count"`
+	Replies    []*Comment `gorm:"-" json:"replies"`
 }
 
-// AddComment 新增评论
+// 评论审核状态
+const (
+	CommentApproved = 1 // 审核通过
+	CommentPending  = 2 // 待审核
+	CommentSpam     = 3 // 垃圾评论
+)
+
+// commentMaxLen 评论内容的最大长度，与 content 字段一致
+const commentMaxLen = 500
+
+// initCommentContent 按评论白名单过滤升级前保存的评论，只处理含有标签的记录
+func initCommentContent() {
+	var comments []Comment
+	// SELECT id, content FROM comment WHERE content LIKE '%<%';
+	db.Unscoped().Select("id, content").Where("content LIKE ?", "%<%").Find(&comments)
+	for _, comment := range comments {
+		clean := sanitize.Comment(comment.Content)
+		// 过滤时会给链接补充属性，超出字段长度时退化为纯文本
+		if utf8.RuneCountInString(clean) > commentMaxLen {
+			clean = sanitize.Text(comment.Content)
+			if runes := []rune(clean); len(runes) > commentMaxLen {
+				clean = string(runes[:commentMaxLen])
+			}
+		}
+		if clean != comment.Content {
+			db.Unscoped().Model(&Comment{}).Where("id = ?", comment.ID).UpdateColumn("content", clean)
+		}
+	}
+}
+
+// avatarHash 按 Gravatar 的规则计算邮箱的哈希值
+func avatarHash(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return ""
+	}
+	sum := md5.Sum([]byte(email))
+	return hex.EncodeToString(sum[:])
+}
+
+// avatarUrl 头像地址，没有邮箱时使用默认头像
+func avatarUrl(hash string) string {
+	return utils.CommentAvatarUrl + hash + "?d=identicon"
+}
+
+// commentStatus 按站点设置决定新评论的审核状态，默认需要审核
+// 开启“只有首次评论需要审核”时，登录用户按账号、游客按昵称与邮箱查找已通过审核的评论
+func commentStatus(data *Comment) int8 {
+	if data.UserId > 0 && GetSettingBool(SettingCommentAutoApproveUser) {
+		return CommentApproved
+	}
+	if GetSettingBool(SettingCommentFirstApproval) {
+		var approved int64
+		tx := db.Model(&Comment{}).Where("status = ?", CommentApproved)
+		if data.UserId > 0 {
+			// SELECT COUNT(*) FROM comment WHERE status = 1 AND user_id = 10 AND deleted_at IS NULL;
+			tx = tx.Where("user_id = ?", data.UserId)
+		} else {
+			// SELECT COUNT(*) FROM comment WHERE status = 1 AND user_id = 0 AND username = '路人' AND email = 'a@example.com' AND deleted_at IS NULL;
+			tx = tx.Where("user_id = 0 AND username = ? AND email = ?", data.Username, data.Email)
+		}
+		tx.Count(&approved)
+		if approved > 0 {
+			return CommentApproved
+		}
+	}
+	return CommentPending
+}
+
+// AddComment 新增评论，文章标题以数据库为准，内容按评论白名单过滤
+// UserId 为 0 表示游客评论，审核状态由站点设置决定
 func AddComment(data *Comment) int {
+	data.Content = sanitize.Comment(data.Content)
+	if strings.TrimSpace(data.Content) == "" {
+		return errmsg.ERROR_COMMENT_EMPTY
+	}
+	if utf8.RuneCountInString(data.Content) > commentMaxLen {
+		return errmsg.ERROR_COMMENT_TOO_LONG
+	}
+	if data.UserId == 0 {
+		if GetSettingBool(SettingCommentGuestDisabled) {
+			return errmsg.ERROR_COMMENT_GUEST_OFF
+		}
+		// 游客不能冒用注册用户的用户名
+		if CheckUser(data.Username) != errmsg.SUCCESS {
+			return errmsg.ERROR_COMMENT_NAME_USED
+		}
+	} else {
+		var user User
+		// 登录用户使用账号邮箱生成头像 SELECT email FROM user WHERE id = 10 LIMIT 1;
+		db.Select("email").Where("id = ?", data.UserId).Limit(1).Find(&user)
+		data.Email = user.Email
+	}
+	data.AvatarHash = avatarHash(data.Email)
+	moderateComment(data)
+
+	var art Article
+	// 只能评论已发布的文章 SELECT id, title FROM article WHERE id = 5 AND status = 3 LIMIT 1;
+	db.Scopes(published).Select("id, title").Where("id = ?", data.ArticleId).Limit(1).Find(&art)
+	if art.ID == 0 {
+		return errmsg.ERROR_ART_NOT_EXIST
+	}
+	data.Title = art.Title
+
 	err = db.Create(&data).Error
 	/**
 	-- 假设新增评论的 user_id=10、article_id=5、content="很棒的文章"、status=0（待审核）
@@ -27,9 +160,41 @@ func AddComment(data *Comment) int {
 	if err != nil {
 		return errmsg.ERROR
 	}
+	if data.Status == CommentApproved {
+		// UPDATE article SET comment_count = comment_count + 1 WHERE id = 5;
+		db.Model(&Article{}).Where("id = ?", data.ArticleId).UpdateColumn("comment_count", gorm.Expr("comment_count + ?", 1))
+	}
+	data.Avatar = avatarUrl(data.AvatarHash)
 	return errmsg.SUCCESS
 }
 
+// ReplyComment 回复评论，只能回复已通过审核且未删除的评论
+// 超过层级上限时挂到被回复评论的上一层，并记录被回复的用户名
+func ReplyComment(parentId int, data *Comment) int {
+	var parent Comment
+	// SELECT id, article_id, parent_id, root_id, depth, username FROM comment WHERE id = 8 AND status = 1 AND removed = false LIMIT 1;
+	db.Select("id, article_id, parent_id, root_id, depth, username").
+		Where("id = ? AND status = ? AND removed = ?", parentId, CommentApproved, false).Limit(1).Find(&parent)
+	if parent.ID == 0 {
+		return errmsg.ERROR_COMMENT_NOT_EXIST
+	}
+
+	data.ArticleId = parent.ArticleId
+	data.RootId = parent.RootId
+	if data.RootId == 0 {
+		data.RootId = parent.ID
+	}
+	data.ReplyTo = parent.Username
+	if parent.Depth < utils.CommentMaxDepth {
+		data.ParentId = parent.ID
+		data.Depth = parent.Depth + 1
+	} else {
+		data.ParentId = parent.ParentId
+		data.Depth = parent.Depth
+	}
+	return AddComment(data)
+}
+
 // GetComment 查询单个评论
 func GetComment(id int) (Comment, int) {
 	var comment Comment
@@ -68,7 +233,7 @@ func GetCommentList(pageSize int, pageNum int) ([]Comment, int64, int) {
 	ORDER BY comments.created_at DESC  -- 按创建时间倒序
 	LIMIT 10 OFFSET 0;  -- 分页：每页10条，第1页（OFFSET=(1-1)*10=0）
 	*/
-	err = db.Model(&commentList).Limit(pageSize).Offset((pageNum - 1) * pageSize).Order("Created_At DESC").Select("comment.id, article.title,user_id,article_id, user.username, comment.content, comment.status,comment.created_at,comment.deleted_at").Joins("LEFT JOIN article ON comment.article_id = article.id").Joins("LEFT JOIN user ON comment.user_id = user.id").Scan(&commentList).Error
+	err = db.Model(&commentList).Limit(pageSize).Offset((pageNum - 1) * pageSize).Order("Created_At DESC").Select("comment.id, article.title,user_id,article_id, IFNULL(user.username, comment.username) AS username, comment.email, comment.website, comment.ip, comment.content, comment.status, comment.spam_score, comment.spam_reason, comment.reviewed,comment.parent_id,comment.reply_to,comment.removed,comment.created_at,comment.deleted_at").Joins("LEFT JOIN article ON comment.article_id = article.id").Joins("LEFT JOIN user ON comment.user_id = user.id").Scan(&commentList).Error
 	if err != nil {
 		return commentList, 0, errmsg.ERROR
 	}
@@ -88,71 +253,177 @@ func GetCommentCount(id int) int64 {
 	return total
 }
 
-// GetCommentListFront 展示页面获取评论列表
-func GetCommentListFront(id int, pageSize int, pageNum int) ([]Comment, int64, int) {
-	var commentList []Comment
+// commentFrontColumns 前台评论列表查询的字段
+// 游客评论没有关联的用户，使用评论中保存的昵称
+const commentFrontColumns = "comment.id, user_id, article_id, IFNULL(user.username, comment.username) AS username, comment.website, comment.avatar_hash, comment.content, comment.status, comment.parent_id, comment.root_id, comment.depth, comment.reply_to, comment.removed, comment.created_at"
+
+// GetCommentListFront 展示页面获取评论列表，按讨论串分页
+// 每页返回若干条顶层评论，回复按时间顺序嵌套在 Replies 中
+func GetCommentListFront(id int, pageSize int, pageNum int) ([]*Comment, int64, int) {
+	var roots []*Comment
 	var total int64
+	// SELECT COUNT(*) FROM comment WHERE article_id = 5 AND parent_id = 0 AND status = 1 AND deleted_at IS NULL;
+	db.Model(&Comment{}).Where("article_id = ? AND parent_id = 0 AND status = ?", id, CommentApproved).Count(&total)
 	/**
-	-- 1. 统计总条数（指定文章+已通过）
-	SELECT COUNT(*) AS total FROM comments
-	WHERE article_id = 5 AND status = 1;  -- 假设 article_id=5
+	SELECT comment.id, user_id, article_id, user.username, comment.content, comment.status, comment.parent_id, ...
+	FROM comment LEFT JOIN user ON comment.user_id = user.id
+	WHERE comment.article_id = 5 AND comment.parent_id = 0 AND comment.status = 1 AND comment.deleted_at IS NULL
+	ORDER BY comment.created_at DESC LIMIT 10 OFFSET 0;
 	*/
-	db.Find(&Comment{}).Where("article_id = ?", id).Where("status = ?", 1).Count(&total)
+	err = db.Model(&Comment{}).Select(commentFrontColumns).Joins("LEFT JOIN user ON comment.user_id = user.id").
+		Where("comment.article_id = ? AND comment.parent_id = 0 AND comment.status = ?", id, CommentApproved).
+		Order("comment.created_at DESC").Limit(pageSize).Offset((pageNum - 1) * pageSize).Scan(&roots).Error
+	if err != nil {
+		return roots, 0, errmsg.ERROR
+	}
+	if len(roots) == 0 {
+		return []*Comment{}, total, errmsg.SUCCESS
+	}
+
+	rootIds := make([]uint, len(roots))
+	for i, c := range roots {
+		rootIds[i] = c.ID
+	}
+	var replies []*Comment
 	/**
-	-- 2. 分页查询（假设 pageSize=10，pageNum=1）
-	SELECT
-	  comments.id,
-	  articles.title,
-	  comments.user_id,
-	  comments.article_id,
-	  users.username,
-	  comments.content,
-	  comments.status,
-	  comments.created_at,
-	  comments.deleted_at
-	FROM comments
-	LEFT JOIN articles ON comments.article_id = articles.id
-	LEFT JOIN users ON comments.user_id = users.id
-	WHERE comments.article_id = 5 AND comments.status = 1  -- 条件：指定文章+已通过
-	ORDER BY comments.created_at DESC
-	LIMIT 10 OFFSET 0;
+	SELECT ... FROM comment LEFT JOIN user ON comment.user_id = user.id
+	WHERE comment.root_id IN (3, 7) AND comment.status = 1 AND comment.deleted_at IS NULL
+	ORDER BY comment.created_at ASC;
 	*/
-	err = db.Model(&Comment{}).Limit(pageSize).Offset((pageNum-1)*pageSize).Order("Created_At DESC").Select("comment.id, article.title, user_id, article_id, user.username, comment.content, comment.status,comment.created_at,comment.deleted_at").Joins("LEFT JOIN article ON comment.article_id = article.id").Joins("LEFT JOIN user ON comment.user_id = user.id").Where("article_id = ?",
-		id).Where("status = ?", 1).Scan(&commentList).Error
+	err = db.Model(&Comment{}).Select(commentFrontColumns).Joins("LEFT JOIN user ON comment.user_id = user.id").
+		Where("comment.root_id IN ? AND comment.status = ?", rootIds, CommentApproved).
+		Order("comment.created_at ASC").Scan(&replies).Error
 	if err != nil {
-		return commentList, 0, errmsg.ERROR
+		return roots, 0, errmsg.ERROR
 	}
-	return commentList, total, errmsg.SUCCESS
+
+	nodes := make(map[uint]*Comment, len(roots)+len(replies))
+	for _, c := range append(roots, replies...) {
+		c.Replies = []*Comment{}
+		if c.Removed {
+			c.Username = ""
+			c.Content = ""
+			c.Website = ""
+			c.AvatarHash = ""
+		}
+		c.Avatar = avatarUrl(c.AvatarHash)
+		nodes[c.ID] = c
+	}
+	for _, c := range replies {
+		// 上层评论被撤下审核时，其下的回复一并隐藏
+		parent, ok := nodes[c.ParentId]
+		if !ok || !attached(nodes, parent) {
+			continue
+		}
+		parent.Replies = append(parent.Replies, c)
+		for p := parent; p != nil; p = nodes[p.ParentId] {
+			p.ReplyCount++
+		}
+	}
+	return roots, total, errmsg.SUCCESS
+}
+
+// attached 判断评论能否沿 ParentId 追溯到顶层评论
+func attached(nodes map[uint]*Comment, c *Comment) bool {
+	for c.ParentId != 0 {
+		parent, ok := nodes[c.ParentId]
+		if !ok {
+			return false
+		}
+		c = parent
+	}
+	return true
 }
 
 // 编辑评论（暂不允许编辑评论）
 
 // DeleteComment 删除评论
+// 有回复的评论保留为占位，只清空内容，回复仍能看到上下文；
+// 没有回复时直接删除，并清理因此不再有回复的已删除占位
 func DeleteComment(id uint) int {
 	var comment Comment
+	// SELECT id, parent_id, article_id, status FROM comment WHERE id = 8 LIMIT 1;
+	db.Select("id, parent_id, article_id, status").Where("id = ?", id).Limit(1).Find(&comment)
+	if comment.ID == 0 {
+		return errmsg.ERROR_COMMENT_NOT_EXIST
+	}
+
+	var replies int64
+	// SELECT COUNT(*) FROM comment WHERE parent_id = 8 AND deleted_at IS NULL;
+	db.Model(&Comment{}).Where("parent_id = ?", id).Count(&replies)
+	if replies > 0 {
+		// UPDATE comment SET content = '', removed = true WHERE id = 8;
+		err = db.Model(&Comment{}).Where("id = ?", id).Updates(map[string]interface{}{"content": "", "removed": true}).Error
+		if err != nil {
+			return errmsg.ERROR
+		}
+		return errmsg.SUCCESS
+	}
+
+	// 删除评论与清理占位在同一事务中进行，已通过审核的评论同时减少文章评论数
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteComment(tx, comment); err != nil {
+			return err
+		}
+		for parentId := comment.ParentId; parentId != 0; {
+			var parent Comment
+			// SELECT id, parent_id, article_id, status FROM comment WHERE id = 3 AND removed = true LIMIT 1;
+			tx.Select("id, parent_id, article_id, status").Where("id = ? AND removed = ?", parentId, true).Limit(1).Find(&parent)
+			if parent.ID == 0 {
+				break
+			}
+			tx.Model(&Comment{}).Where("parent_id = ?", parent.ID).Count(&replies)
+			if replies > 0 {
+				break
+			}
+			if err := deleteComment(tx, parent); err != nil {
+				return err
+			}
+			parentId = parent.ParentId
+		}
+		return nil
+	})
+	if err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+// deleteComment 软删除评论，已通过审核的评论同时减少文章评论数，与审核时增加评论数对应
+func deleteComment(tx *gorm.DB, comment Comment) error {
 	/**
 	-- 软删除（逻辑删除，假设评论 ID=8）
 	UPDATE comments
 	SET deleted_at = CURRENT_TIMESTAMP
 	WHERE id = 8;
-
-	-- 若开启物理删除（使用 Unscoped()），则为：
-	DELETE FROM comments WHERE id = 8;
 	*/
-	err = db.Where("id = ?", id).Delete(&comment).Error
-	if err != nil {
-		return errmsg.ERROR
+	if err := tx.Where("id = ?", comment.ID).Delete(&Comment{}).Error; err != nil {
+		return err
 	}
-	return errmsg.SUCCESS
+	if comment.Status != CommentApproved {
+		return nil
+	}
+	// UPDATE article SET comment_count = comment_count - 1 WHERE id = 5;
+	return tx.Model(&Article{}).Where("id = ?", comment.ArticleId).UpdateColumn("comment_count", gorm.Expr("comment_count - ?", 1)).Error
+}
+
+// approvedBefore 评论当前是否已通过审核，更改审核状态时据此调整文章评论数
+func approvedBefore(id int) bool {
+	var comment Comment
+	// SELECT status FROM comment WHERE id = 5 LIMIT 1;
+	db.Select("status").Where("id = ?", id).Limit(1).Find(&comment)
+	return comment.Status == CommentApproved
 }
 
-// CheckComment 通过评论
+// CheckComment 通过评论，记为人工审核的正常评论
 func CheckComment(id int, data *Comment) int {
 	var comment Comment
 	var res Comment
 	var article Article
 	var maps = make(map[string]interface{})
 	maps["status"] = data.Status
+	maps["reviewed"] = true
+	approved := approvedBefore(id)
 	/**
 	-- 先更新评论状态
 	UPDATE comments
@@ -169,20 +440,24 @@ func CheckComment(id int, data *Comment) int {
 	SET comment_count = comment_count + 1
 	WHERE id = 5;
 	*/
-	db.Model(&article).Where("id = ?", res.ArticleId).UpdateColumn("comment_count", gorm.Expr("comment_count + ?", 1))
+	if !approved && res.Status == CommentApproved {
+		db.Model(&article).Where("id = ?", res.ArticleId).UpdateColumn("comment_count", gorm.Expr("comment_count + ?", 1))
+	}
 	if err != nil {
 		return errmsg.ERROR
 	}
 	return errmsg.SUCCESS
 }
 
-// UncheckComment 撤下评论
+// UncheckComment 撤下评论，撤下不代表是垃圾评论，不再参与分类器训练
 func UncheckComment(id int, data *Comment) int {
 	var comment Comment
 	var res Comment
 	var article Article
 	var maps = make(map[string]interface{})
 	maps["status"] = data.Status
+	maps["reviewed"] = false
+	approved := approvedBefore(id)
 
 	/**
 	-- 1. 更新评论状态（假设评论 ID=6，更新 status=0）
@@ -199,9 +474,27 @@ func UncheckComment(id int, data *Comment) int {
 	WHERE id = 5;
 	*/
 	err = db.Model(&comment).Where("id = ?", id).Updates(maps).First(&res).Error
-	db.Model(&article).Where("id = ?", res.ArticleId).UpdateColumn("comment_count", gorm.Expr("comment_count - ?", 1))
+	if approved && res.Status != CommentApproved {
+		db.Model(&article).Where("id = ?", res.ArticleId).UpdateColumn("comment_count", gorm.Expr("comment_count - ?", 1))
+	}
+	if err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+// SpamComment 标记为垃圾评论，记为人工审核的垃圾评论
+func SpamComment(id int) int {
+	var res Comment
+	approved := approvedBefore(id)
+	// UPDATE comment SET status = 3, reviewed = true WHERE id = 6;
+	err = db.Model(&Comment{}).Where("id = ?", id).Updates(map[string]interface{}{"status": CommentSpam, "reviewed": true}).First(&res).Error
 	if err != nil {
 		return errmsg.ERROR
 	}
+	if approved {
+		// UPDATE article SET comment_count = comment_count - 1 WHERE id = 5;
+		db.Model(&Article{}).Where("id = ?", res.ArticleId).UpdateColumn("comment_count", gorm.Expr("comment_count - ?", 1))
+	}
 	return errmsg.SUCCESS
 }
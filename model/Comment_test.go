@@ -0,0 +1,48 @@
+package model
+
+import (
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"testing"
+)
+
+func TestDeleteCommentCount(t *testing.T) {
+	useTestDb(t, &Article{}, &Comment{})
+
+	art := Article{Title: "Gin搭建博客", CommentCount: 2}
+	if err := db.Create(&art).Error; err != nil {
+		t.Fatal(err)
+	}
+	root := Comment{ArticleId: art.ID, Content: "root", Status: CommentApproved}
+	db.Create(&root)
+	reply := Comment{ArticleId: art.ID, ParentId: root.ID, Content: "reply", Status: CommentApproved}
+	db.Create(&reply)
+	pending := Comment{ArticleId: art.ID, Content: "pending", Status: CommentPending}
+	db.Create(&pending)
+
+	count := func() int {
+		var a Article
+		db.Select("comment_count").Where("id = ?", art.ID).Find(&a)
+		return a.CommentCount
+	}
+
+	steps := []struct {
+		name string
+		id   uint
+		want int
+	}{
+		// 待审核的评论没有计入评论数
+		{"待审核", pending.ID, 2},
+		// 有回复的评论保留为占位，仍计入评论数
+		{"保留占位", root.ID, 2},
+		// 删除最后一条回复时占位一并清理，两条都从评论数中减去
+		{"清理占位", reply.ID, 0},
+	}
+	for _, step := range steps {
+		if code := DeleteComment(step.id); code != errmsg.SUCCESS {
+			t.Fatalf("%s: code = %d", step.name, code)
+		}
+		if got := count(); got != step.want {
+			t.Errorf("%s: comment_count = %d，应为 %d", step.name, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"github.com/wejectchen/ginblog/utils"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"strings"
+	"time"
+)
+
+// EmailVerify 邮箱验证令牌，只保存摘要，一次有效
+// 记录发送时的邮箱，更换邮箱后旧链接自动失效
+type EmailVerify struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserId    uint       `gorm:"index;not null" json:"user_id"`
+	Email     string     `gorm:"type:varchar(100);not null" json:"email"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// CreateEmailVerifyToken 生成邮箱验证令牌，之前未使用的令牌全部作废
+func CreateEmailVerifyToken(user User) (string, int) {
+	now := time.Now()
+	// UPDATE email_verify SET used_at = CURRENT_TIMESTAMP WHERE user_id = 5 AND used_at IS NULL;
+	db.Model(&EmailVerify{}).Where("user_id = ? AND used_at IS NULL", user.ID).Update("used_at", now)
+
+	token := RandomToken(32)
+	data := EmailVerify{
+		UserId:    user.ID,
+		Email:     user.Email,
+		TokenHash: HashToken(token),
+		ExpiresAt: now.Add(utils.EmailVerifyExpire),
+	}
+	err = db.Create(&data).Error
+	if err != nil {
+		return "", errmsg.ERROR
+	}
+	return token, errmsg.SUCCESS
+}
+
+// VerifyEmail 校验令牌并将账号标记为已验证
+func VerifyEmail(token string) (User, int) {
+	var verify EmailVerify
+	// SELECT * FROM email_verify WHERE token_hash = '...' LIMIT 1;
+	db.Where("token_hash = ?", HashToken(token)).Limit(1).Find(&verify)
+	if verify.ID == 0 || verify.UsedAt != nil || time.Now().After(verify.ExpiresAt) {
+		return User{}, errmsg.ERROR_VERIFY_INVALID
+	}
+	user, _ := GetUser(int(verify.UserId))
+	if user.ID == 0 || user.Email == "" || !strings.EqualFold(user.Email, verify.Email) {
+		return User{}, errmsg.ERROR_VERIFY_INVALID
+	}
+
+	/**
+	-- 条件更新保证令牌只能使用一次
+	UPDATE email_verify SET used_at = CURRENT_TIMESTAMP WHERE id = 3 AND used_at IS NULL;
+	*/
+	now := time.Now()
+	res := db.Model(&EmailVerify{}).Where("id = ? AND used_at IS NULL", verify.ID).Update("used_at", now)
+	if res.Error != nil {
+		return user, errmsg.ERROR
+	}
+	if res.RowsAffected == 0 {
+		return User{}, errmsg.ERROR_VERIFY_INVALID
+	}
+
+	/**
+	UPDATE user SET status = 1, email_verified_at = CURRENT_TIMESTAMP
+	WHERE id = 5;
+	*/
+	err = db.Model(&User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"status":            UserActive,
+		"email_verified_at": now,
+	}).Error
+	if err != nil {
+		return user, errmsg.ERROR
+	}
+	user.Status = UserActive
+	user.EmailVerifiedAt = &now
+	return user, errmsg.SUCCESS
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"regexp"
+	"time"
+)
+
+// UserIdentity 第三方登录身份，与本站用户绑定
+type UserIdentity struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserId    uint      `gorm:"index;not null" json:"user_id"`
+	Provider  string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_provider_subject" json:"provider"`
+	Subject   string    `gorm:"type:varchar(191);not null;uniqueIndex:idx_provider_subject" json:"subject"`
+	Username  string    `gorm:"type:varchar(100)" json:"username"`
+	Email     string    `gorm:"type:varchar(100)" json:"email"`
+}
+
+// GetIdentityUser 查询第三方身份绑定的用户
+func GetIdentityUser(provider string, subject string) (User, int) {
+	var identity UserIdentity
+	var user User
+	// SELECT * FROM user_identity WHERE provider = 'github' AND subject = '123' LIMIT 1;
+	db.Where("provider = ? AND subject = ?", provider, subject).Limit(1).Find(&identity)
+	if identity.ID == 0 {
+		return user, errmsg.ERROR_USER_NOT_EXIST
+	}
+	user, _ = GetUser(int(identity.UserId))
+	if user.ID == 0 {
+		return user, errmsg.ERROR_USER_NOT_EXIST
+	}
+	return user, errmsg.SUCCESS
+}
+
+// LinkIdentity 为用户绑定第三方身份
+func LinkIdentity(userId uint, identity *UserIdentity) int {
+	var exist UserIdentity
+	db.Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).Limit(1).Find(&exist)
+	if exist.ID > 0 {
+		if exist.UserId == userId {
+			return errmsg.SUCCESS
+		}
+		return errmsg.ERROR_IDENTITY_LINKED
+	}
+	identity.UserId = userId
+	err = db.Create(identity).Error
+	if err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+var usernameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// CreateIdentityUser 第三方身份首次登录时创建本站用户
+// 用户名取自第三方用户名，冲突时追加数字；密码随机生成，之后可通过找回密码设置
+func CreateIdentityUser(identity *UserIdentity) (User, int) {
+	base := usernameInvalid.ReplaceAllString(identity.Username, "")
+	if len(base) > 7 {
+		base = base[:7]
+	}
+	if len(base) < 4 {
+		base = identity.Provider + "_" + base
+	}
+	username := base
+	for CheckUser(username) != errmsg.SUCCESS {
+		username = base + "_" + RandomToken(2)
+	}
+
+	user := User{
+		Username: username,
+		Password: RandomToken(16),
+		Role:     RoleReader,
+	}
+	// 邮箱已被其他账号使用时不写入，避免同一邮箱对应多个账号
+	if identity.Email != "" && GetUserByAccount(identity.Email).ID == 0 {
+		user.Email = identity.Email
+	}
+	if code := CreateUser(&user); code != errmsg.SUCCESS {
+		return user, code
+	}
+	if code := LinkIdentity(user.ID, identity); code != errmsg.SUCCESS {
+		return user, code
+	}
+	return user, errmsg.SUCCESS
+}
+
+// GetUserIdentities 查询用户绑定的第三方身份
+func GetUserIdentities(userId uint) ([]UserIdentity, int) {
+	var identities []UserIdentity
+	err = db.Where("user_id = ?", userId).Find(&identities).Error
+	if err != nil {
+		return identities, errmsg.ERROR
+	}
+	return identities, errmsg.SUCCESS
+}
+
+// DeleteIdentity 解除绑定
+func DeleteIdentity(userId uint, id int) int {
+	// DELETE FROM user_identity WHERE id = 3 AND user_id = 5;
+	res := db.Where("id = ? AND user_id = ?", id, userId).Delete(&UserIdentity{})
+	if res.Error != nil {
+		return errmsg.ERROR
+	}
+	if res.RowsAffected == 0 {
+		return errmsg.ERROR_FORBIDDEN
+	}
+	return errmsg.SUCCESS
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+	"testing"
+)
+
+// useTestDb 使用内存中的 SQLite 代替 MySQL，只迁移测试需要的表
+func useTestDb(t *testing.T, models ...interface{}) {
+	t.Helper()
+	test, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		NamingStrategy: schema.NamingStrategy{SingularTable: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = test.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	old := db
+	db = test
+	t.Cleanup(func() { db = old })
+}
+
+func TestLinkIdentity(t *testing.T) {
+	useTestDb(t, &UserIdentity{})
+
+	identity := func() *UserIdentity {
+		return &UserIdentity{Provider: "oidc", Subject: "10001", Username: "octocat"}
+	}
+	if code := LinkIdentity(1, identity()); code != errmsg.SUCCESS {
+		t.Fatalf("首次绑定: code = %d", code)
+	}
+	// 重复绑定到同一用户视为成功，不产生重复记录
+	if code := LinkIdentity(1, identity()); code != errmsg.SUCCESS {
+		t.Errorf("重复绑定: code = %d", code)
+	}
+	if code := LinkIdentity(2, identity()); code != errmsg.ERROR_IDENTITY_LINKED {
+		t.Errorf("绑定到其他用户: code = %d，应为 %d", code, errmsg.ERROR_IDENTITY_LINKED)
+	}
+	// 同一 subject 在不同提供方是不同的身份
+	if code := LinkIdentity(2, &UserIdentity{Provider: "github", Subject: "10001"}); code != errmsg.SUCCESS {
+		t.Errorf("其他提供方的同名 subject: code = %d", code)
+	}
+
+	identities, _ := GetUserIdentities(1)
+	if len(identities) != 1 || identities[0].Provider != "oidc" || identities[0].UserId != 1 {
+		t.Fatalf("用户 1 的绑定 = %+v", identities)
+	}
+	if code := DeleteIdentity(2, int(identities[0].ID)); code != errmsg.ERROR_FORBIDDEN {
+		t.Errorf("解除他人的绑定: code = %d，应为 %d", code, errmsg.ERROR_FORBIDDEN)
+	}
+	if code := DeleteIdentity(1, int(identities[0].ID)); code != errmsg.SUCCESS {
+		t.Errorf("解除绑定: code = %d", code)
+	}
+	if code := LinkIdentity(2, identity()); code != errmsg.SUCCESS {
+		t.Errorf("解除后重新绑定到其他用户: code = %d", code)
+	}
+}
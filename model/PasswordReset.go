@@ -0,0 +1,66 @@
+package model
+
+import (
+	"github.com/wejectchen/ginblog/utils"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"time"
+)
+
+// PasswordReset 找回密码令牌，只保存摘要，一次有效
+type PasswordReset struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserId    uint       `gorm:"index;not null" json:"user_id"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// GetUserByAccount 按用户名或邮箱查询用户
+func GetUserByAccount(account string) User {
+	var user User
+	// SELECT * FROM user WHERE username = 'admin' OR email = 'admin' LIMIT 1;
+	db.Where("username = ? OR email = ?", account, account).Limit(1).Find(&user)
+	return user
+}
+
+// CreatePasswordReset 生成找回密码令牌，之前未使用的令牌全部作废
+func CreatePasswordReset(userId uint) (string, int) {
+	now := time.Now()
+	// UPDATE password_reset SET used_at = CURRENT_TIMESTAMP WHERE user_id = 5 AND used_at IS NULL;
+	db.Model(&PasswordReset{}).Where("user_id = ? AND used_at IS NULL", userId).Update("used_at", now)
+
+	token := RandomToken(32)
+	data := PasswordReset{
+		UserId:    userId,
+		TokenHash: HashToken(token),
+		ExpiresAt: now.Add(utils.ResetTokenExpire),
+	}
+	err = db.Create(&data).Error
+	if err != nil {
+		return "", errmsg.ERROR
+	}
+	return token, errmsg.SUCCESS
+}
+
+// ResetPassword 使用找回密码令牌重置密码，成功后注销该用户的全部登录
+func ResetPassword(token string, password string) int {
+	var reset PasswordReset
+	db.Where("token_hash = ?", HashToken(token)).Limit(1).Find(&reset)
+	if reset.ID == 0 || reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return errmsg.ERROR_RESET_TOKEN_INVALID
+	}
+
+	/**
+	-- 条件更新保证令牌只能使用一次
+	UPDATE password_reset SET used_at = CURRENT_TIMESTAMP WHERE id = 3 AND used_at IS NULL;
+	*/
+	res := db.Model(&PasswordReset{}).Where("id = ? AND used_at IS NULL", reset.ID).Update("used_at", time.Now())
+	if res.Error != nil {
+		return errmsg.ERROR
+	}
+	if res.RowsAffected == 0 {
+		return errmsg.ERROR_RESET_TOKEN_INVALID
+	}
+	return ChangePassword(int(reset.UserId), password, "")
+}
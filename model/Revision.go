@@ -0,0 +1,237 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"github.com/wejectchen/ginblog/utils"
+	"github.com/wejectchen/ginblog/utils/diff"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// ArticleRevision 文章修订版本，每次保存文章都会追加一条，写入后不可修改
+type ArticleRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	ArticleId uint      `gorm:"uniqueIndex:idx_revision_version;not null" json:"article_id"`
+	// Version 文章内的版本号，从 1 开始递增
+	Version int  `gorm:"uniqueIndex:idx_revision_version;not null" json:"version"`
+	UserId  uint `json:"user_id"`
+	// Username 查询时关联用户表得到，不入库
+	Username string `gorm:"->;-:migration" json:"username"`
+	Title    string `gorm:"type:varchar(100)" json:"title"`
+	Cid      int    `json:"cid"`
+	Desc     string `gorm:"type:varchar(200)" json:"desc"`
+	Content  string `gorm:"type:longtext" json:"content,omitempty"`
+	Format   string `gorm:"type:varchar(10)" json:"format"`
+	Img      string `gorm:"type:varchar(100)" json:"img"`
+	// RestoredFrom 由哪个版本恢复而来，0 表示普通编辑
+	RestoredFrom int `json:"restored_from"`
+}
+
+// RevisionDiff 两个版本之间的差异
+type RevisionDiff struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+	// Fields 标题、分类等字段的变化，格式为 {"字段": [修改前, 修改后]}
+	Fields map[string][2]interface{} `json:"fields"`
+	// Content 正文的统一格式差异（diff -u）
+	Content string `json:"content"`
+}
+
+var ErrRevisionImmutable = errors.New("文章修订版本不允许修改")
+
+// BeforeUpdate 修订版本写入后不可修改，过期版本只能按保留策略删除
+func (r *ArticleRevision) BeforeUpdate(_ *gorm.DB) error {
+	return ErrRevisionImmutable
+}
+
+// saveRevision 记录文章当前内容，与最新版本相同时不重复记录
+func saveRevision(articleId uint, userId uint, restoredFrom int, createdAt time.Time) int {
+	var art Article
+	db.Select("id, title, cid, `desc`, content, format, img").Where("id = ?", articleId).Limit(1).Find(&art)
+	if art.ID == 0 {
+		return errmsg.ERROR_ART_NOT_EXIST
+	}
+
+	var latest ArticleRevision
+	// SELECT * FROM article_revision WHERE article_id = 5 ORDER BY version DESC LIMIT 1;
+	db.Where("article_id = ?", articleId).Order("version desc").Limit(1).Find(&latest)
+	if latest.ID != 0 && restoredFrom == 0 && latest.Title == art.Title && latest.Cid == art.Cid &&
+		latest.Desc == art.Desc && latest.Content == art.Content && latest.Format == art.Format && latest.Img == art.Img {
+		return errmsg.SUCCESS
+	}
+
+	data := ArticleRevision{
+		CreatedAt:    createdAt,
+		ArticleId:    articleId,
+		Version:      latest.Version + 1,
+		UserId:       userId,
+		Title:        art.Title,
+		Cid:          art.Cid,
+		Desc:         art.Desc,
+		Content:      art.Content,
+		Format:       art.Format,
+		Img:          art.Img,
+		RestoredFrom: restoredFrom,
+	}
+	/**
+	INSERT INTO article_revision (created_at, article_id, version, user_id, title, cid, `desc`, content, format, img, restored_from)
+	VALUES (CURRENT_TIMESTAMP, 5, 3, 1, '标题', 2, '描述', '正文', 'markdown', 'img.png', 0);
+	*/
+	err = db.Create(&data).Error
+	if err != nil {
+		return errmsg.ERROR
+	}
+	pruneRevisions(articleId)
+	return errmsg.SUCCESS
+}
+
+// ensureBaseRevision 升级前创建的文章没有修订记录，首次编辑前先保存原始内容
+func ensureBaseRevision(articleId uint) {
+	var count int64
+	db.Model(&ArticleRevision{}).Where("article_id = ?", articleId).Count(&count)
+	if count > 0 {
+		return
+	}
+	var art Article
+	db.Select("id, user_id, updated_at").Where("id = ?", articleId).Limit(1).Find(&art)
+	if art.ID != 0 {
+		saveRevision(articleId, art.UserId, 0, art.UpdatedAt)
+	}
+}
+
+// GetRevisions 分页查询文章的修订版本，不包含正文
+func GetRevisions(articleId int, pageSize int, pageNum int) ([]ArticleRevision, int64, int) {
+	var revisions []ArticleRevision
+	var total int64
+	/**
+	SELECT article_revision.id, article_revision.created_at, article_id, version, user_id, title, cid, `desc`, format, img, restored_from, user.username
+	FROM article_revision LEFT JOIN user ON user.id = article_revision.user_id
+	WHERE article_id = 5 ORDER BY version DESC LIMIT 10 OFFSET 0;
+	*/
+	db.Model(&ArticleRevision{}).Where("article_id = ?", articleId).Count(&total)
+	err = db.Model(&ArticleRevision{}).
+		Select("article_revision.id, article_revision.created_at, article_id, version, user_id, title, cid, `desc`, format, img, restored_from, user.username").
+		Joins("LEFT JOIN user ON user.id = article_revision.user_id").
+		Where("article_id = ?", articleId).Order("version desc").
+		Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&revisions).Error
+	if err != nil {
+		return revisions, 0, errmsg.ERROR
+	}
+	return revisions, total, errmsg.SUCCESS
+}
+
+// GetRevision 查询单个修订版本，version 为 0 时返回最新版本
+func GetRevision(articleId int, version int) (ArticleRevision, int) {
+	var revision ArticleRevision
+	tx := db.Select("article_revision.*, user.username").
+		Joins("LEFT JOIN user ON user.id = article_revision.user_id").
+		Where("article_id = ?", articleId)
+	if version > 0 {
+		tx = tx.Where("version = ?", version)
+	}
+	// SELECT article_revision.*, user.username FROM article_revision LEFT JOIN user ON ... WHERE article_id = 5 AND version = 3 LIMIT 1;
+	tx.Order("version desc").Limit(1).Find(&revision)
+	if revision.ID == 0 {
+		return revision, errmsg.ERROR_REVISION_NOT_EXIST
+	}
+	return revision, errmsg.SUCCESS
+}
+
+// DiffRevisions 比较两个版本，to 为 0 时与最新版本比较
+func DiffRevisions(articleId int, from int, to int) (RevisionDiff, int) {
+	a, code := GetRevision(articleId, from)
+	if code != errmsg.SUCCESS {
+		return RevisionDiff{}, code
+	}
+	b, code := GetRevision(articleId, to)
+	if code != errmsg.SUCCESS {
+		return RevisionDiff{}, code
+	}
+
+	fields := make(map[string][2]interface{})
+	if a.Title != b.Title {
+		fields["title"] = [2]interface{}{a.Title, b.Title}
+	}
+	if a.Cid != b.Cid {
+		fields["cid"] = [2]interface{}{a.Cid, b.Cid}
+	}
+	if a.Desc != b.Desc {
+		fields["desc"] = [2]interface{}{a.Desc, b.Desc}
+	}
+	if a.Format != b.Format {
+		fields["format"] = [2]interface{}{a.Format, b.Format}
+	}
+	if a.Img != b.Img {
+		fields["img"] = [2]interface{}{a.Img, b.Img}
+	}
+	return RevisionDiff{
+		From:   a.Version,
+		To:     b.Version,
+		Fields: fields,
+		Content: diff.Unified(
+			fmt.Sprintf("v%d", a.Version), fmt.Sprintf("v%d", b.Version), a.Content, b.Content, 3),
+	}, errmsg.SUCCESS
+}
+
+// RestoreRevision 将文章恢复为指定版本的内容，恢复操作本身会记录为新版本
+// 恢复的内容按当前操作者的权限重新过滤
+func RestoreRevision(articleId int, version int, userId uint, trusted bool) int {
+	revision, code := GetRevision(articleId, version)
+	if code != errmsg.SUCCESS {
+		return code
+	}
+	return editArt(articleId, &Article{
+		Title:   revision.Title,
+		Cid:     revision.Cid,
+		Desc:    revision.Desc,
+		Content: revision.Content,
+		Format:  revision.Format,
+		Img:     revision.Img,
+	}, userId, revision.Version, trusted)
+}
+
+// pruneRevisions 按 [revision] 配置清理旧版本，articleId 为 0 时清理全部文章
+// 每篇文章的最新版本始终保留
+func pruneRevisions(articleId uint) {
+	var conds []string
+	var args []interface{}
+	if utils.RevisionMaxCount > 0 {
+		conds = append(conds, "r.version <= t.latest - ?")
+		args = append(args, utils.RevisionMaxCount)
+	}
+	if utils.RevisionMaxAge > 0 {
+		conds = append(conds, "(r.created_at < ? AND r.version < t.latest)")
+		args = append(args, time.Now().Add(-utils.RevisionMaxAge))
+	}
+	if len(conds) == 0 {
+		return
+	}
+
+	scope, scopeArgs := "", []interface{}{}
+	if articleId > 0 {
+		scope, scopeArgs = "WHERE article_id = ?", []interface{}{articleId}
+	}
+	/**
+	DELETE r FROM article_revision r
+	JOIN (SELECT article_id, MAX(version) AS latest FROM article_revision WHERE article_id = 5 GROUP BY article_id) t
+	  ON r.article_id = t.article_id
+	WHERE r.version <= t.latest - 50 OR (r.created_at < '保留期限' AND r.version < t.latest);
+	*/
+	sql := "DELETE r FROM article_revision r JOIN (SELECT article_id, MAX(version) AS latest FROM article_revision " +
+		scope + " GROUP BY article_id) t ON r.article_id = t.article_id WHERE " + strings.Join(conds, " OR ")
+	if err := db.Exec(sql, append(scopeArgs, args...)...).Error; err != nil {
+		fmt.Println("清理文章修订版本失败：", err)
+	}
+}
+
+// RunRevisionPruner 定期按保留策略清理所有文章的旧版本
+func RunRevisionPruner() {
+	for {
+		pruneRevisions(0)
+		time.Sleep(time.Hour)
+	}
+}
@@ -0,0 +1,200 @@
+package model
+
+import (
+	"github.com/wejectchen/ginblog/utils/errmsg"
+)
+
+// 内置角色，ID 与 user.role 对应
+const (
+	RoleAdmin     = 1 // 管理员
+	RoleReader    = 2 // 订阅者（普通用户）
+	RoleEditor    = 3 // 编辑
+	RoleAuthor    = 4 // 作者
+	RoleModerator = 5 // 评论审核员
+)
+
+// 权限码
+const (
+	PermAdminAccess     = "admin:access"
+	PermArticleWrite    = "article:write"
+	PermArticleManage   = "article:manage"
+	PermArticlePublish  = "article:publish"
+	PermArticleHtml     = "article:html"
+	PermCategoryManage  = "category:manage"
+	PermCommentModerate = "comment:moderate"
+	PermUserManage      = "user:manage"
+	PermSettingManage   = "setting:manage"
+	PermFileUpload      = "file:upload"
+	PermAuditRead       = "audit:read"
+)
+
+// Permission 权限
+type Permission struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Code string `gorm:"type:varchar(50);uniqueIndex;not null" json:"code"`
+	Name string `gorm:"type:varchar(50)" json:"name"`
+}
+
+// Role 角色
+type Role struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	Name        string       `gorm:"type:varchar(20);uniqueIndex;not null" json:"name"`
+	Label       string       `gorm:"type:varchar(20)" json:"label"`
+	Permissions []Permission `gorm:"many2many:role_permission" json:"permissions"`
+}
+
+var defaultPermissions = []Permission{
+	{Code: PermAdminAccess, Name: "登录后台"},
+	{Code: PermArticleWrite, Name: "撰写文章"},
+	{Code: PermArticleManage, Name: "管理所有文章"},
+	{Code: PermArticlePublish, Name: "发布文章"},
+	{Code: PermArticleHtml, Name: "文章嵌入音视频与 iframe"},
+	{Code: PermCategoryManage, Name: "管理分类"},
+	{Code: PermCommentModerate, Name: "审核评论"},
+	{Code: PermUserManage, Name: "管理用户"},
+	{Code: PermSettingManage, Name: "站点设置"},
+	{Code: PermFileUpload, Name: "上传文件"},
+	{Code: PermAuditRead, Name: "查看审计日志"},
+}
+
+var defaultRoles = []struct {
+	Role  Role
+	Perms []string
+}{
+	{Role{ID: RoleAdmin, Name: "admin", Label: "管理员"}, nil},
+	{Role{ID: RoleReader, Name: "reader", Label: "订阅者"}, []string{}},
+	{Role{ID: RoleEditor, Name: "editor", Label: "编辑"}, []string{
+		PermAdminAccess, PermArticleWrite, PermArticleManage, PermArticlePublish, PermArticleHtml,
+		PermCategoryManage, PermCommentModerate, PermFileUpload,
+	}},
+	{Role{ID: RoleAuthor, Name: "author", Label: "作者"}, []string{
+		PermAdminAccess, PermArticleWrite, PermFileUpload,
+	}},
+	{Role{ID: RoleModerator, Name: "moderator", Label: "评论审核员"}, []string{
+		PermAdminAccess, PermCommentModerate,
+	}},
+}
+
+// InitRbac 初始化内置角色与权限
+// 已存在的角色保留后台修改过的权限，只补充本次启动新增的默认权限（如升级后新加的权限）；
+// 管理员角色始终拥有全部权限
+func InitRbac() {
+	var all []Permission
+	// added 本次启动新建的权限，升级前已存在的内置角色需要补上
+	added := make(map[string]bool)
+	for _, p := range defaultPermissions {
+		perm := p
+		res := db.Where(Permission{Code: perm.Code}).Attrs(Permission{Name: perm.Name}).FirstOrCreate(&perm)
+		if res.Error == nil && res.RowsAffected > 0 {
+			added[perm.Code] = true
+		}
+		all = append(all, perm)
+	}
+
+	for _, r := range defaultRoles {
+		role := r.Role
+		var total int64
+		db.Model(&Role{}).Where("id = ?", role.ID).Count(&total)
+		if role.ID == RoleAdmin {
+			if total == 0 {
+				db.Create(&role)
+			}
+			_ = db.Model(&role).Association("Permissions").Replace(all)
+			continue
+		}
+		var perms []Permission
+		for _, p := range all {
+			for _, code := range r.Perms {
+				if p.Code == code && (total == 0 || added[code]) {
+					perms = append(perms, p)
+				}
+			}
+		}
+		if total > 0 {
+			if len(perms) > 0 {
+				_ = db.Model(&role).Association("Permissions").Append(perms)
+			}
+			continue
+		}
+		role.Permissions = perms
+		db.Create(&role)
+	}
+}
+
+// CheckRole 查询角色是否存在
+func CheckRole(id int) int {
+	var total int64
+	// SELECT COUNT(*) FROM role WHERE id = 3;
+	db.Model(&Role{}).Where("id = ?", id).Count(&total)
+	if total == 0 {
+		return errmsg.ERROR_ROLE_NOT_EXIST
+	}
+	return errmsg.SUCCESS
+}
+
+// GetRoles 查询角色列表及其权限
+func GetRoles() ([]Role, int) {
+	var roles []Role
+	err = db.Preload("Permissions").Order("id").Find(&roles).Error
+	if err != nil {
+		return roles, errmsg.ERROR
+	}
+	return roles, errmsg.SUCCESS
+}
+
+// GetPermissions 查询全部权限
+func GetPermissions() ([]Permission, int) {
+	var perms []Permission
+	err = db.Order("id").Find(&perms).Error
+	if err != nil {
+		return perms, errmsg.ERROR
+	}
+	return perms, errmsg.SUCCESS
+}
+
+// GetRolePermissions 查询角色拥有的权限码
+func GetRolePermissions(roleId int) []string {
+	var codes []string
+	/**
+	SELECT permission.code FROM permission
+	JOIN role_permission ON role_permission.permission_id = permission.id
+	WHERE role_permission.role_id = 3;
+	*/
+	db.Model(&Permission{}).Joins("JOIN role_permission ON role_permission.permission_id = permission.id").
+		Where("role_permission.role_id = ?", roleId).Pluck("permission.code", &codes)
+	return codes
+}
+
+// HasPermission 判断角色是否拥有某项权限
+func HasPermission(roleId int, perm string) bool {
+	for _, code := range GetRolePermissions(roleId) {
+		if code == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRolePermissions 设置角色权限
+func SetRolePermissions(id int, codes []string) int {
+	if id == RoleAdmin {
+		return errmsg.ERROR_ROLE_LOCKED
+	}
+	if code := CheckRole(id); code != errmsg.SUCCESS {
+		return code
+	}
+	var perms []Permission
+	if len(codes) > 0 {
+		db.Where("code IN ?", codes).Find(&perms)
+	}
+	association := db.Model(&Role{ID: uint(id)}).Association("Permissions")
+	if len(perms) == 0 {
+		err = association.Clear()
+	} else {
+		err = association.Replace(perms)
+	}
+	if err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
@@ -0,0 +1,264 @@
+package model
+
+import (
+	"fmt"
+	"github.com/wejectchen/ginblog/utils"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"github.com/wejectchen/ginblog/utils/search"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// ArtSearch 文章搜索条件，零值表示不筛选，时间按发布时间筛选
+type ArtSearch struct {
+	Keyword string
+	Cid     int
+	TagId   uint
+	From    time.Time
+	To      time.Time
+}
+
+// ArtSearchHit 搜索结果，TitleHtml 与 Snippet 中的命中词用 <mark> 标出，其余内容已转义
+type ArtSearchHit struct {
+	Article
+	Score     float64 `json:"score"`
+	TitleHtml string  `json:"title_html"`
+	Snippet   string  `json:"snippet"`
+}
+
+// snippetWidth 摘要长度（字符数）
+const snippetWidth = 120
+
+// searchEngine 当前使用的搜索后端，由配置 [search] Engine 决定
+var searchEngine search.Engine
+
+// suggester 搜索框的输入建议，包含已发布文章的标题与标签，启动时从数据库重建
+var suggester = search.NewSuggester()
+
+// initSearch 初始化搜索后端与搜索建议
+func initSearch() {
+	switch utils.SearchEngine {
+	case "mysql":
+		searchEngine = mysqlSearch{}
+		// ALTER TABLE article ADD FULLTEXT INDEX idx_article_fulltext (title, `desc`, content_text) WITH PARSER ngram;
+		if !db.Migrator().HasIndex(&Article{}, "idx_article_fulltext") {
+			err := db.Exec("ALTER TABLE article ADD FULLTEXT INDEX idx_article_fulltext (title, `desc`, content_text) WITH PARSER ngram").Error
+			if err != nil {
+				fmt.Println("创建全文索引失败，请确认 MySQL 版本不低于 5.7.6：", err)
+			}
+		}
+	default:
+		searchEngine = search.NewMemoryEngine()
+	}
+
+	var ids []uint
+	// SELECT id FROM article WHERE status = 3 AND deleted_at IS NULL;
+	db.Model(&Article{}).Scopes(published).Pluck("id", &ids)
+	indexArticles(ids)
+
+	var tags []TagCount
+	/**
+	SELECT tag.*, COUNT(article_tag.article_id) AS count FROM tag
+	LEFT JOIN article_tag ON article_tag.tag_id = tag.id GROUP BY tag.id;
+	*/
+	db.Model(&Tag{}).Select("tag.*, COUNT(article_tag.article_id) AS count").
+		Joins("LEFT JOIN article_tag ON article_tag.tag_id = tag.id").Group("tag.id").Scan(&tags)
+	for _, tag := range tags {
+		suggester.Put(search.Suggestion{Type: "tag", Id: tag.ID, Text: tag.Name, Slug: tag.Slug}, int(tag.Count))
+	}
+}
+
+// suggestTags 按标签当前状态更新搜索建议，使用次数越多越靠前，已删除的标签从建议中移除
+func suggestTags(ids ...uint) {
+	for _, id := range ids {
+		var tag TagCount
+		// SELECT tag.*, COUNT(article_tag.article_id) AS count FROM tag LEFT JOIN article_tag ON ... WHERE tag.id = 3 GROUP BY tag.id;
+		db.Model(&Tag{}).Select("tag.*, COUNT(article_tag.article_id) AS count").
+			Joins("LEFT JOIN article_tag ON article_tag.tag_id = tag.id").Where("tag.id = ?", id).Group("tag.id").Scan(&tag)
+		if tag.ID == 0 {
+			suggester.Remove("tag", id)
+			continue
+		}
+		suggester.Put(search.Suggestion{Type: "tag", Id: tag.ID, Text: tag.Name, Slug: tag.Slug}, int(tag.Count))
+	}
+}
+
+// indexArticles 按文章当前状态更新索引与搜索建议，已发布的文章写入索引，其余从索引中移除
+func indexArticles(ids []uint) {
+	for start := 0; start < len(ids); start += 100 {
+		end := start + 100
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var arts []Article
+		// SELECT id, title, slug, `desc`, content_text, cid, read_count, published_at FROM article WHERE id IN (1, 2, 3) AND status = 3 AND deleted_at IS NULL;
+		db.Scopes(published).Select("id, title, slug, `desc`, content_text, cid, read_count, published_at").Preload("Tags").
+			Where("id IN ?", ids[start:end]).Find(&arts)
+
+		found := make(map[uint]bool, len(arts))
+		for _, art := range arts {
+			found[art.ID] = true
+			doc := search.Document{
+				ID:      art.ID,
+				Title:   art.Title,
+				Desc:    art.Desc,
+				Content: art.ContentText,
+				Cid:     art.Cid,
+			}
+			if art.PublishedAt != nil {
+				doc.PublishedAt = *art.PublishedAt
+			}
+			for _, tag := range art.Tags {
+				doc.TagIds = append(doc.TagIds, tag.ID)
+			}
+			if err := searchEngine.Index(doc); err != nil {
+				fmt.Println("更新搜索索引失败：", err)
+			}
+			suggester.Put(search.Suggestion{Type: "article", Id: art.ID, Text: art.Title, Slug: art.Slug}, art.ReadCount)
+		}
+		for _, id := range ids[start:end] {
+			if !found[id] {
+				_ = searchEngine.Delete(id)
+				suggester.Remove("article", id)
+			}
+		}
+	}
+}
+
+// indexArticle 更新单篇文章的索引
+func indexArticle(id uint) {
+	indexArticles([]uint{id})
+}
+
+// SearchArticle 全文搜索已发布的文章，按相关度排序并生成高亮摘要
+func SearchArticle(q ArtSearch, pageSize int, pageNum int) ([]ArtSearchHit, int, int64) {
+	hits, total, err := searchEngine.Search(search.Query{
+		Text:   q.Keyword,
+		Cid:    q.Cid,
+		TagId:  q.TagId,
+		From:   q.From,
+		To:     q.To,
+		Offset: (pageNum - 1) * pageSize,
+		Limit:  pageSize,
+	})
+	if err != nil {
+		return nil, errmsg.ERROR, 0
+	}
+	if len(hits) == 0 {
+		return []ArtSearchHit{}, errmsg.SUCCESS, total
+	}
+
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	var arts []Article
+	/**
+	SELECT article.id, title, slug, img, created_at, updated_at, `desc`, content_text, comment_count, read_count, status, published_at, Category.name
+	FROM article LEFT JOIN category Category ON article.cid = Category.id
+	WHERE article.id IN (5, 3, 8) AND article.status = 3;
+	*/
+	err = db.Scopes(published).Select("article.id, title, slug, img, created_at, updated_at, `desc`, content_text, comment_count, read_count, status, published_at, Category.name").
+		Joins("Category").Preload("Tags").Where("article.id IN ?", ids).Find(&arts).Error
+	if err != nil {
+		return nil, errmsg.ERROR, 0
+	}
+	byId := make(map[uint]Article, len(arts))
+	for _, art := range arts {
+		byId[art.ID] = art
+	}
+
+	// 按搜索后端返回的相关度顺序输出
+	list := make([]ArtSearchHit, 0, len(hits))
+	for _, hit := range hits {
+		art, ok := byId[hit.ID]
+		if !ok {
+			continue
+		}
+		// 正文中没有命中时（只命中了标题或描述）使用描述作为摘要
+		snippet := search.Highlight(art.ContentText, q.Keyword, snippetWidth)
+		if !strings.Contains(snippet, "<mark>") && art.Desc != "" {
+			snippet = search.Highlight(art.Desc, q.Keyword, snippetWidth)
+		}
+		list = append(list, ArtSearchHit{
+			Article:   art,
+			Score:     hit.Score,
+			TitleHtml: search.Highlight(art.Title, q.Keyword, 0),
+			Snippet:   snippet,
+		})
+	}
+	return list, errmsg.SUCCESS, total
+}
+
+// SuggestSearch 搜索框输入建议，按前缀匹配文章标题与标签，支持全拼与拼音首字母
+func SuggestSearch(prefix string, limit int) []search.Suggestion {
+	return suggester.Suggest(prefix, limit)
+}
+
+// mysqlSearch 基于 MySQL 全文索引（ngram 分词）的搜索后端
+// 直接查询文章表，无需单独维护索引，适合多实例部署
+type mysqlSearch struct{}
+
+func (mysqlSearch) Index(_ search.Document) error { return nil }
+
+func (mysqlSearch) Delete(_ uint) error { return nil }
+
+func (mysqlSearch) Search(q search.Query) ([]search.Hit, int64, error) {
+	// 每个检索词都必须出现，如 +"使用" +"gin" +"搭建博客"
+	var words []string
+	for _, word := range strings.Fields(q.Text) {
+		word = strings.NewReplacer(`"`, " ", `\`, " ").Replace(word)
+		if strings.TrimSpace(word) != "" {
+			words = append(words, `+"`+word+`"`)
+		}
+	}
+	if len(words) == 0 {
+		return nil, 0, nil
+	}
+	against := strings.Join(words, " ")
+
+	filter := func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Where("MATCH (title, `desc`, content_text) AGAINST (? IN BOOLEAN MODE)", against)
+		if q.Cid > 0 {
+			tx = tx.Where("cid = ?", q.Cid)
+		}
+		if q.TagId > 0 {
+			tx = tx.Where("EXISTS (SELECT 1 FROM article_tag WHERE article_tag.article_id = article.id AND article_tag.tag_id = ?)", q.TagId)
+		}
+		if !q.From.IsZero() {
+			tx = tx.Where("published_at >= ?", q.From)
+		}
+		if !q.To.IsZero() {
+			tx = tx.Where("published_at < ?", q.To)
+		}
+		return tx
+	}
+
+	var total int64
+	if err := db.Model(&Article{}).Scopes(published, filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if q.Offset < 0 {
+		return nil, total, nil
+	}
+	var hits []struct {
+		Id    uint
+		Score float64
+	}
+	/**
+	SELECT id, MATCH (title, `desc`, content_text) AGAINST ('+"gin" +"博客"' IN BOOLEAN MODE) AS score FROM article
+	WHERE status = 3 AND MATCH (title, `desc`, content_text) AGAINST ('+"gin" +"博客"' IN BOOLEAN MODE) AND cid = 2
+	ORDER BY score DESC, published_at DESC LIMIT 10 OFFSET 0;
+	*/
+	err := db.Model(&Article{}).Scopes(published, filter).Select("id, MATCH (title, `desc`, content_text) AGAINST (? IN BOOLEAN MODE) AS score", against).
+		Order("score DESC, published_at DESC").Limit(q.Limit).Offset(q.Offset).Scan(&hits).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	out := make([]search.Hit, len(hits))
+	for i, hit := range hits {
+		out[i] = search.Hit{ID: hit.Id, Score: hit.Score}
+	}
+	return out, total, nil
+}
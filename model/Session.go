@@ -0,0 +1,129 @@
+package model
+
+import (
+	"github.com/wejectchen/ginblog/utils"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"strings"
+	"time"
+)
+
+// Session 登录会话，一次登录对应一个刷新令牌家族
+type Session struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UserId     uint      `gorm:"index;not null" json:"user_id"`
+	Family     string    `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	Scope      string    `gorm:"type:varchar(20)" json:"scope"`
+	Device     string    `gorm:"type:varchar(100)" json:"device"`
+	Ip         string    `gorm:"type:varchar(64)" json:"ip"`
+	LastIp     string    `gorm:"type:varchar(64)" json:"last_ip"`
+	UserAgent  string    `gorm:"type:varchar(500)" json:"user_agent"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// ExpiresAt 刷新令牌到期时间，每次刷新顺延
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+	Revoked   bool      `gorm:"not null;default:false" json:"revoked"`
+	// Current 是否为发起请求的会话，不入库
+	Current bool `gorm:"-" json:"current"`
+}
+
+// CreateSession 记录新的登录会话
+func CreateSession(userId uint, family string, scope string, ip string, userAgent string) int {
+	if len(userAgent) > 500 {
+		userAgent = userAgent[:500]
+	}
+	now := time.Now()
+	data := Session{
+		UserId:     userId,
+		Family:     family,
+		Scope:      scope,
+		Device:     parseDevice(userAgent),
+		Ip:         ip,
+		LastIp:     ip,
+		UserAgent:  userAgent,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(utils.RefreshTokenExpire),
+	}
+	/**
+	INSERT INTO session (user_id, family, scope, device, ip, last_ip, user_agent, last_seen_at, expires_at, created_at)
+	VALUES (5, 'family', 'admin', 'Chrome / Windows', '1.2.3.4', '1.2.3.4', 'Mozilla/5.0 ...', CURRENT_TIMESTAMP, '过期时间', CURRENT_TIMESTAMP);
+	*/
+	err = db.Create(&data).Error
+	if err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+// TouchSession 更新会话最近活跃时间与IP，refreshed 为 true 时顺延过期时间
+func TouchSession(family string, ip string, refreshed bool) {
+	now := time.Now()
+	maps := map[string]interface{}{
+		"last_seen_at": now,
+		"last_ip":      ip,
+	}
+	if refreshed {
+		maps["expires_at"] = now.Add(utils.RefreshTokenExpire)
+	}
+	// UPDATE session SET last_seen_at = CURRENT_TIMESTAMP, last_ip = '1.2.3.4' WHERE family = 'family';
+	db.Model(&Session{}).Where("family = ?", family).Updates(maps)
+}
+
+// GetSessions 查询用户未失效的会话
+func GetSessions(userId uint) ([]Session, int) {
+	var sessions []Session
+	/**
+	SELECT * FROM session
+	WHERE user_id = 5 AND revoked = false AND expires_at > CURRENT_TIMESTAMP
+	ORDER BY last_seen_at DESC;
+	*/
+	err = db.Where("user_id = ? AND revoked = ? AND expires_at > ?", userId, false, time.Now()).
+		Order("last_seen_at desc").Find(&sessions).Error
+	if err != nil {
+		return sessions, errmsg.ERROR
+	}
+	return sessions, errmsg.SUCCESS
+}
+
+// GetSession 查询单个会话
+func GetSession(id int) (Session, int) {
+	var session Session
+	db.Where("id = ?", id).Limit(1).Find(&session)
+	if session.ID == 0 {
+		return session, errmsg.ERROR_SESSION_NOT_EXIST
+	}
+	return session, errmsg.SUCCESS
+}
+
+// RevokeSession 注销会话，该会话的刷新令牌与访问令牌同时失效
+func RevokeSession(id int) int {
+	session, code := GetSession(id)
+	if code != errmsg.SUCCESS {
+		return code
+	}
+	return RevokeTokenFamily(session.Family)
+}
+
+// parseDevice 从 User-Agent 中粗略识别浏览器与系统，仅用于展示
+func parseDevice(ua string) string {
+	browser, os := "未知浏览器", "未知系统"
+	for _, b := range []struct{ key, name string }{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"MicroMessenger", "微信"},
+		{"Firefox/", "Firefox"}, {"Chrome/", "Chrome"}, {"Safari/", "Safari"},
+		{"curl/", "curl"}, {"PostmanRuntime", "Postman"},
+	} {
+		if strings.Contains(ua, b.key) {
+			browser = b.name
+			break
+		}
+	}
+	for _, o := range []struct{ key, name string }{
+		{"Android", "Android"}, {"iPhone", "iOS"}, {"iPad", "iPadOS"},
+		{"Windows", "Windows"}, {"Mac OS X", "macOS"}, {"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.key) {
+			os = o.name
+			break
+		}
+	}
+	return browser + " / " + os
+}
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"github.com/wejectchen/ginblog/utils/errmsg"
+)
+
+// 站点设置项
+const (
+	// SettingRequireAdminMfa 管理员账号必须开启两步验证
+	SettingRequireAdminMfa = "require_admin_mfa"
+	// SettingRequireEmailVerify 前台注册的账号必须验证邮箱后才能登录
+	SettingRequireEmailVerify = "require_email_verify"
+	// SettingCommentGuestDisabled 关闭游客评论，只允许登录后评论
+	SettingCommentGuestDisabled = "comment_guest_disabled"
+	// SettingCommentAutoApproveUser 登录用户的评论无需审核
+	SettingCommentAutoApproveUser = "comment_auto_approve_user"
+	// SettingCommentFirstApproval 只有首次评论需要审核，已有评论通过审核的评论者无需再审核
+	SettingCommentFirstApproval = "comment_first_approval"
+)
+
+// 站点设置的默认值，未在数据库中保存时使用
+var defaultSettings = map[string]string{
+	SettingRequireAdminMfa:    "false",
+	SettingRequireEmailVerify: "true",
+	// 默认所有评论都需要审核
+	SettingCommentGuestDisabled:   "false",
+	SettingCommentAutoApproveUser: "false",
+	SettingCommentFirstApproval:   "false",
+}
+
+// Setting 站点设置（键值对）
+type Setting struct {
+	Key   string `gorm:"type:varchar(50);primaryKey" json:"key"`
+	Value string `gorm:"type:varchar(500)" json:"value"`
+}
+
+// GetSetting 查询单个设置项
+func GetSetting(key string) string {
+	var setting Setting
+	// SELECT * FROM setting WHERE `key` = 'require_admin_mfa' LIMIT 1;
+	db.Where("`key` = ?", key).Limit(1).Find(&setting)
+	if setting.Key == "" {
+		return defaultSettings[key]
+	}
+	return setting.Value
+}
+
+// GetSettingBool 查询开关类设置项
+func GetSettingBool(key string) bool {
+	return GetSetting(key) == "true"
+}
+
+// GetSettings 查询全部设置项
+func GetSettings() (map[string]string, int) {
+	var settings []Setting
+	data := make(map[string]string)
+	for k, v := range defaultSettings {
+		data[k] = v
+	}
+	err = db.Find(&settings).Error
+	if err != nil {
+		return data, errmsg.ERROR
+	}
+	for _, s := range settings {
+		data[s.Key] = s.Value
+	}
+	return data, errmsg.SUCCESS
+}
+
+// UpdateSettings 更新设置项，只接受已定义的键
+func UpdateSettings(data map[string]string) int {
+	for k, v := range data {
+		if _, ok := defaultSettings[k]; !ok {
+			continue
+		}
+		// INSERT INTO setting (`key`, value) VALUES ('require_admin_mfa', 'true') ON DUPLICATE KEY UPDATE value = 'true';
+		err = db.Save(&Setting{Key: k, Value: v}).Error
+		if err != nil {
+			return errmsg.ERROR
+		}
+	}
+	return errmsg.SUCCESS
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"github.com/wejectchen/ginblog/utils/slug"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strconv"
+	"time"
+)
+
+// 使用 slug 的对象类型
+const (
+	SlugArticle  = "article"
+	SlugCategory = "category"
+)
+
+// SlugRedirect 旧 slug 到对象的映射，修改 slug 后旧链接通过 301 跳转到新地址
+type SlugRedirect struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Type      string    `gorm:"type:varchar(20);uniqueIndex:idx_slug_redirect;not null" json:"type"`
+	OldSlug   string    `gorm:"type:varchar(100);uniqueIndex:idx_slug_redirect;not null" json:"old_slug"`
+	TargetId  uint      `gorm:"index;not null" json:"target_id"`
+}
+
+// uniqueSlug 根据 source 生成在 model 对应的表中不重复的 slug，冲突时追加序号
+// 已删除的记录同样占用 slug，id 为当前记录，新增时传 0
+func uniqueSlug(tx *gorm.DB, model interface{}, source string, fallback string, id uint) string {
+	base := slug.Make(source)
+	if base == "" {
+		base = fallback
+	}
+	s := base
+	for i := 2; ; i++ {
+		var count int64
+		// SELECT COUNT(*) FROM article WHERE slug = 'hello-world' AND id <> 5;
+		tx.Unscoped().Model(model).Where("slug = ? AND id <> ?", s, id).Count(&count)
+		if count == 0 {
+			return s
+		}
+		s = base + "-" + strconv.Itoa(i)
+	}
+}
+
+// changeSlug 修改 slug 后记录旧 slug 的跳转，新 slug 若曾是跳转地址则删除该跳转
+func changeSlug(tx *gorm.DB, kind string, id uint, oldSlug string, newSlug string) error {
+	if oldSlug == newSlug {
+		return nil
+	}
+	// DELETE FROM slug_redirect WHERE type = 'article' AND old_slug = 'new-slug';
+	if err := tx.Where("type = ? AND old_slug = ?", kind, newSlug).Delete(&SlugRedirect{}).Error; err != nil {
+		return err
+	}
+	if oldSlug == "" {
+		return nil
+	}
+	/**
+	INSERT INTO slug_redirect (created_at, type, old_slug, target_id) VALUES (CURRENT_TIMESTAMP, 'article', 'old-slug', 5)
+	ON DUPLICATE KEY UPDATE target_id = VALUES(target_id), created_at = VALUES(created_at);
+	*/
+	return tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"target_id", "created_at"}),
+	}).Create(&SlugRedirect{Type: kind, OldSlug: oldSlug, TargetId: id}).Error
+}
+
+// FindSlugRedirect 查询旧 slug 跳转的目标ID，不存在时返回 0
+func FindSlugRedirect(kind string, oldSlug string) uint {
+	var redirect SlugRedirect
+	// SELECT * FROM slug_redirect WHERE type = 'article' AND old_slug = 'old-slug' LIMIT 1;
+	db.Where("type = ? AND old_slug = ?", kind, oldSlug).Limit(1).Find(&redirect)
+	return redirect.TargetId
+}
+
+// initSlugs 升级前的文章和分类没有 slug，在建立唯一索引前先补全
+func initSlugs() {
+	m := db.Migrator()
+	if m.HasTable(&Article{}) {
+		if !m.HasColumn(&Article{}, "Slug") {
+			_ = m.AddColumn(&Article{}, "Slug")
+		}
+		var arts []Article
+		// SELECT id, title FROM article WHERE slug = '' OR slug IS NULL;
+		db.Unscoped().Select("id, title").Where("slug = '' OR slug IS NULL").Find(&arts)
+		for _, art := range arts {
+			s := uniqueSlug(db, &Article{}, art.Title, "article-"+strconv.Itoa(int(art.ID)), art.ID)
+			db.Unscoped().Model(&Article{}).Where("id = ?", art.ID).UpdateColumn("slug", s)
+		}
+	}
+	if m.HasTable(&Category{}) {
+		if !m.HasColumn(&Category{}, "Slug") {
+			_ = m.AddColumn(&Category{}, "Slug")
+		}
+		var cates []Category
+		// SELECT id, name FROM category WHERE slug = '' OR slug IS NULL;
+		db.Select("id, name").Where("slug = '' OR slug IS NULL").Find(&cates)
+		for _, cate := range cates {
+			s := uniqueSlug(db, &Category{}, cate.Name, "category-"+strconv.Itoa(int(cate.ID)), cate.ID)
+			db.Model(&Category{}).Where("id = ?", cate.ID).UpdateColumn("slug", s)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package model
+
+import (
+	"fmt"
+	"github.com/wejectchen/ginblog/utils"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"github.com/wejectchen/ginblog/utils/search"
+	"github.com/wejectchen/ginblog/utils/spam"
+	"gorm.io/gorm"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 启发式规则的加分
+const (
+	linkPenalty   = 0.2 // 超出 MaxLinks 的每个链接
+	repeatPenalty = 0.4 // 近期出现过相同内容
+)
+
+// SpamStats 垃圾评论分类器的训练情况，Ready 表示样本足够，已启用贝叶斯分类
+type SpamStats struct {
+	Ham   int  `json:"ham"`
+	Spam  int  `json:"spam"`
+	Ready bool `json:"ready"`
+}
+
+var (
+	spamMu         sync.RWMutex
+	spamClassifier = spam.NewClassifier()
+	spamBlocklist  = &spam.Blocklist{}
+)
+
+// initSpam 加载黑名单，并用人工审核过的评论训练分类器
+func initSpam() {
+	blocklist, err := spam.LoadBlocklist(utils.SpamBlocklist)
+	if err != nil {
+		fmt.Println("加载垃圾评论黑名单失败：", err)
+	}
+	spamMu.Lock()
+	spamBlocklist = blocklist
+	spamMu.Unlock()
+	TrainSpam()
+}
+
+// TrainSpam 重新训练垃圾评论分类器，通过审核的评论为正常样本，标记为垃圾的评论为垃圾样本
+// 已删除的评论同样参与训练
+func TrainSpam() (SpamStats, int) {
+	classifier := spam.NewClassifier()
+	var batch []Comment
+	// SELECT id, content, status FROM comment WHERE reviewed = true AND status IN (1, 3) AND content <> '' ORDER BY id LIMIT 500;
+	err = db.Unscoped().Select("id, content, status").
+		Where("reviewed = ? AND status IN ? AND content <> ''", true, []int{CommentApproved, CommentSpam}).
+		FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+			for _, c := range batch {
+				classifier.Train(search.PlainText(c.Content), c.Status == CommentSpam)
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return spamStats(), errmsg.ERROR
+	}
+
+	spamMu.Lock()
+	spamClassifier = classifier
+	spamMu.Unlock()
+	return spamStats(), errmsg.SUCCESS
+}
+
+func spamStats() SpamStats {
+	spamMu.RLock()
+	defer spamMu.RUnlock()
+	ham, junk := spamClassifier.Docs()
+	return SpamStats{
+		Ham:   ham,
+		Spam:  junk,
+		Ready: ham >= utils.SpamMinSamples && junk >= utils.SpamMinSamples,
+	}
+}
+
+// spamScore 计算垃圾评论评分，返回评分与依据
+// 黑名单命中直接记为 1 分；否则以贝叶斯分类的概率为基础（样本不足时为 0.5），再按链接数与重复内容加分
+func spamScore(data *Comment) (float64, []string) {
+	text := search.PlainText(data.Content)
+	spamMu.RLock()
+	blocklist, classifier := spamBlocklist, spamClassifier
+	spamMu.RUnlock()
+
+	if rule := blocklist.Match(text, data.Username, data.Email, data.Website); rule != "" {
+		return 1, []string{"黑名单 " + rule}
+	}
+
+	var reasons []string
+	score := 0.5
+	if spamStats().Ready {
+		score = classifier.Score(text)
+		reasons = append(reasons, fmt.Sprintf("分类器 %.2f", score))
+	}
+	if links := spam.CountLinks(data.Content); links > utils.SpamMaxLinks {
+		score += float64(links-utils.SpamMaxLinks) * linkPenalty
+		reasons = append(reasons, fmt.Sprintf("链接 %d 个", links))
+	}
+	var repeated int64
+	// SELECT COUNT(*) FROM comment WHERE content = '...' AND created_at > '2024-01-01 12:00:00' AND deleted_at IS NULL;
+	db.Model(&Comment{}).Where("content = ? AND created_at > ?", data.Content, time.Now().Add(-utils.SpamRepeatWindow)).Count(&repeated)
+	if repeated > 0 {
+		score += repeatPenalty
+		reasons = append(reasons, fmt.Sprintf("重复内容 %d 次", repeated))
+	}
+	if score > 1 {
+		score = 1
+	}
+	return score, reasons
+}
+
+// moderateComment 按垃圾评论评分决定新评论的审核状态
+// 评分很低时自动通过，较高时必须人工审核，很高时直接标记为垃圾评论，其余情况按站点的评论审核设置处理
+func moderateComment(data *Comment) {
+	score, reasons := spamScore(data)
+	data.SpamScore = score
+	data.SpamReason = strings.Join(reasons, "；")
+	if runes := []rune(data.SpamReason); len(runes) > 200 {
+		data.SpamReason = string(runes[:200])
+	}
+
+	switch {
+	case score >= utils.SpamRejectAbove:
+		data.Status = CommentSpam
+	case score > utils.SpamHoldAbove:
+		data.Status = CommentPending
+	case score < utils.SpamApproveBelow:
+		data.Status = CommentApproved
+	default:
+		data.Status = commentStatus(data)
+	}
+}
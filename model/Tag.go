@@ -0,0 +1,256 @@
+package model
+
+import (
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+)
+
+// Tag 文章标签，与文章多对多关联（article_tag）
+type Tag struct {
+	ID   uint   `gorm:"primary_key;auto_increment" json:"id"`
+	Name string `gorm:"type:varchar(30);uniqueIndex;not null" json:"name"`
+	Slug string `gorm:"type:varchar(64);uniqueIndex;not null" json:"slug"`
+}
+
+// TagCount 标签及其关联的文章数
+type TagCount struct {
+	Tag
+	Count int64 `json:"count"`
+}
+
+// CheckTag 查询标签名是否已被其他标签使用，id 为当前标签，新增时传 0
+func CheckTag(name string, id int) int {
+	var tag Tag
+	// SELECT id FROM tag WHERE name = 'Go' LIMIT 1;
+	db.Select("id").Where("name = ?", name).Limit(1).Find(&tag)
+	if tag.ID > 0 && int(tag.ID) != id {
+		return errmsg.ERROR_TAGNAME_USED
+	}
+	return errmsg.SUCCESS
+}
+
+// CreateTag 新增标签，未指定 slug 时根据名称生成
+func CreateTag(data *Tag) int {
+	data.Name = strings.TrimSpace(data.Name)
+	data.Slug = uniqueSlug(db, &Tag{}, firstNonEmpty(data.Slug, data.Name), "tag", 0)
+	// INSERT INTO tag (name, slug) VALUES ('Go', 'go');
+	err = db.Create(data).Error
+	if err != nil {
+		return errmsg.ERROR
+	}
+	suggestTags(data.ID)
+	return errmsg.SUCCESS
+}
+
+// GetTagInfo 查询单个标签
+func GetTagInfo(id int) (Tag, int) {
+	var tag Tag
+	// SELECT * FROM tag WHERE id = 3 LIMIT 1;
+	db.Where("id = ?", id).Limit(1).Find(&tag)
+	if tag.ID == 0 {
+		return tag, errmsg.ERROR_TAG_NOT_EXIST
+	}
+	return tag, errmsg.SUCCESS
+}
+
+// GetTagBySlug 根据 slug 查询标签
+func GetTagBySlug(s string) (Tag, int) {
+	var tag Tag
+	// SELECT * FROM tag WHERE slug = 'go' LIMIT 1;
+	db.Where("slug = ?", s).Limit(1).Find(&tag)
+	if tag.ID == 0 {
+		return tag, errmsg.ERROR_TAG_NOT_EXIST
+	}
+	return tag, errmsg.SUCCESS
+}
+
+// GetTags 后台分页查询标签，附带关联的文章数（包含未发布的文章）
+func GetTags(name string, pageSize int, pageNum int) ([]TagCount, int64) {
+	var tags []TagCount
+	var total int64
+	tx := db.Model(&Tag{})
+	if name != "" {
+		tx = tx.Where("tag.name LIKE ?", name+"%")
+	}
+	tx.Count(&total)
+	/**
+	SELECT tag.*, COUNT(article.id) AS count FROM tag
+	LEFT JOIN article_tag ON article_tag.tag_id = tag.id
+	LEFT JOIN article ON article.id = article_tag.article_id AND article.deleted_at IS NULL
+	WHERE tag.name LIKE 'go%'
+	GROUP BY tag.id ORDER BY tag.id LIMIT 10 OFFSET 0;
+	*/
+	tx.Select("tag.*, COUNT(article.id) AS count").
+		Joins("LEFT JOIN article_tag ON article_tag.tag_id = tag.id").
+		Joins("LEFT JOIN article ON article.id = article_tag.article_id AND article.deleted_at IS NULL").
+		Group("tag.id").Order("tag.id").
+		Limit(pageSize).Offset((pageNum - 1) * pageSize).Scan(&tags)
+	return tags, total
+}
+
+// GetTagCloud 标签云，只统计已发布的文章，按文章数倒序
+func GetTagCloud(limit int) ([]TagCount, int) {
+	var tags []TagCount
+	/**
+	SELECT tag.*, COUNT(*) AS count FROM tag
+	JOIN article_tag ON article_tag.tag_id = tag.id
+	JOIN article ON article.id = article_tag.article_id
+	WHERE article.status = 3 AND article.deleted_at IS NULL
+	GROUP BY tag.id ORDER BY count DESC, tag.name LIMIT 50;
+	*/
+	err = db.Model(&Tag{}).Select("tag.*, COUNT(*) AS count").
+		Joins("JOIN article_tag ON article_tag.tag_id = tag.id").
+		Joins("JOIN article ON article.id = article_tag.article_id").
+		Where("article.status = ? AND article.deleted_at IS NULL", ArtPublished).
+		Group("tag.id").Order("count DESC, tag.name").Limit(limit).Scan(&tags).Error
+	if err != nil {
+		return tags, errmsg.ERROR
+	}
+	return tags, errmsg.SUCCESS
+}
+
+// EditTag 重命名标签，提交 slug 时同时修改 slug
+// 文章通过标签ID关联，重命名后所有文章自动使用新名称
+func EditTag(id int, data *Tag) int {
+	maps := map[string]interface{}{"name": strings.TrimSpace(data.Name)}
+	if data.Slug != "" {
+		maps["slug"] = uniqueSlug(db, &Tag{}, data.Slug, "tag-"+strconv.Itoa(id), uint(id))
+	}
+	// UPDATE tag SET name = 'Golang', slug = 'golang' WHERE id = 3;
+	err = db.Model(&Tag{}).Where("id = ?", id).Updates(maps).Error
+	if err != nil {
+		return errmsg.ERROR
+	}
+	suggestTags(uint(id))
+	return errmsg.SUCCESS
+}
+
+// MergeTags 将多个标签合并到目标标签，原标签关联的文章改为关联目标标签，随后删除原标签
+func MergeTags(sourceIds []int, targetId int) int {
+	if _, code := GetTagInfo(targetId); code != errmsg.SUCCESS {
+		return code
+	}
+	ids := make([]int, 0, len(sourceIds))
+	for _, id := range sourceIds {
+		if id != targetId {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return errmsg.SUCCESS
+	}
+	arts := tagArticleIds(ids...)
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		/**
+		INSERT IGNORE INTO article_tag (article_id, tag_id)
+		SELECT article_id, 3 FROM article_tag WHERE tag_id IN (4, 5);
+		*/
+		if err := tx.Exec("INSERT IGNORE INTO article_tag (article_id, tag_id) SELECT article_id, ? FROM article_tag WHERE tag_id IN ?",
+			targetId, ids).Error; err != nil {
+			return err
+		}
+		// DELETE FROM article_tag WHERE tag_id IN (4, 5);
+		if err := tx.Exec("DELETE FROM article_tag WHERE tag_id IN ?", ids).Error; err != nil {
+			return err
+		}
+		// DELETE FROM tag WHERE id IN (4, 5);
+		return tx.Where("id IN ?", ids).Delete(&Tag{}).Error
+	})
+	if err != nil {
+		return errmsg.ERROR
+	}
+	indexArticles(arts)
+	suggestTags(uint(targetId))
+	for _, id := range ids {
+		suggestTags(uint(id))
+	}
+	return errmsg.SUCCESS
+}
+
+// DeleteTag 删除标签及其与文章的关联
+func DeleteTag(id int) int {
+	arts := tagArticleIds(id)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// DELETE FROM article_tag WHERE tag_id = 3;
+		if err := tx.Exec("DELETE FROM article_tag WHERE tag_id = ?", id).Error; err != nil {
+			return err
+		}
+		// DELETE FROM tag WHERE id = 3;
+		return tx.Where("id = ?", id).Delete(&Tag{}).Error
+	})
+	if err != nil {
+		return errmsg.ERROR
+	}
+	indexArticles(arts)
+	suggestTags(uint(id))
+	return errmsg.SUCCESS
+}
+
+// tagArticleIds 查询使用了这些标签的文章，标签变更后需要更新它们的搜索索引
+func tagArticleIds(tagIds ...int) []uint {
+	var ids []uint
+	// SELECT DISTINCT article_id FROM article_tag WHERE tag_id IN (4, 5);
+	db.Table("article_tag").Where("tag_id IN ?", tagIds).Distinct().Pluck("article_id", &ids)
+	return ids
+}
+
+// resolveTags 将提交的标签转换为已存在的标签，按ID或名称查找，名称不存在时自动创建
+func resolveTags(input []Tag) ([]Tag, int) {
+	tags := make([]Tag, 0, len(input))
+	seen := make(map[uint]bool)
+	for _, t := range input {
+		var tag Tag
+		name := strings.TrimSpace(t.Name)
+		switch {
+		case t.ID > 0:
+			db.Where("id = ?", t.ID).Limit(1).Find(&tag)
+		case name != "":
+			db.Where("name = ?", name).Limit(1).Find(&tag)
+			if tag.ID == 0 {
+				tag.Name = name
+				if code := CreateTag(&tag); code != errmsg.SUCCESS {
+					// 并发创建同名标签时唯一索引冲突，重新查询一次
+					tag = Tag{}
+					db.Where("name = ?", name).Limit(1).Find(&tag)
+				}
+			}
+		}
+		if tag.ID == 0 {
+			return nil, errmsg.ERROR_TAG_NOT_EXIST
+		}
+		if !seen[tag.ID] {
+			seen[tag.ID] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags, errmsg.SUCCESS
+}
+
+// SetArtTags 替换文章的全部标签
+func SetArtTags(id uint, input []Tag) int {
+	tags, code := resolveTags(input)
+	if code != errmsg.SUCCESS {
+		return code
+	}
+	/**
+	DELETE FROM article_tag WHERE article_id = 5 AND tag_id NOT IN (1, 2);
+	INSERT INTO article_tag (article_id, tag_id) VALUES (5, 1), (5, 2) ON DUPLICATE KEY UPDATE article_id = article_id;
+	*/
+	err = db.Model(&Article{Model: gorm.Model{ID: id}}).Association("Tags").Replace(tags)
+	if err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
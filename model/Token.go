@@ -0,0 +1,176 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/wejectchen/ginblog/utils"
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"time"
+)
+
+// RefreshToken 刷新令牌，同一次登录不断轮换出的令牌属于同一个 Family
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserId    uint       `gorm:"index;not null" json:"user_id"`
+	Family    string     `gorm:"type:varchar(64);index;not null" json:"family"`
+	Scope     string     `gorm:"type:varchar(20)" json:"scope"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	Revoked   bool       `gorm:"not null;default:false" json:"revoked"`
+}
+
+// RevokedToken 已吊销的访问令牌，按 jti 拉黑直到其自然过期
+type RevokedToken struct {
+	Jti       string    `gorm:"type:varchar(64);primaryKey" json:"jti"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+}
+
+// RandomToken 生成 n 字节的随机串（十六进制）
+func RandomToken(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// HashToken 令牌只保存摘要，数据库泄露时无法直接使用
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateRefreshToken 签发刷新令牌，family 为空时开启新的令牌家族
+// scope 区分后台登录与前台读者登录，轮换时保持不变
+func CreateRefreshToken(userId uint, family string, scope string) (string, string, int) {
+	if family == "" {
+		family = RandomToken(16)
+	}
+	token := RandomToken(32)
+	data := RefreshToken{
+		UserId:    userId,
+		Family:    family,
+		Scope:     scope,
+		TokenHash: HashToken(token),
+		ExpiresAt: time.Now().Add(utils.RefreshTokenExpire),
+	}
+	/**
+	INSERT INTO refresh_token (user_id, family, token_hash, expires_at, revoked, created_at)
+	VALUES (5, 'family', 'sha256', '过期时间', false, CURRENT_TIMESTAMP);
+	*/
+	err = db.Create(&data).Error
+	if err != nil {
+		return "", "", errmsg.ERROR
+	}
+	return token, family, errmsg.SUCCESS
+}
+
+// RotateRefreshToken 轮换刷新令牌
+// 已轮换过的令牌再次出现说明令牌泄露，直接吊销整个家族
+func RotateRefreshToken(token string) (RefreshToken, string, int) {
+	var rt RefreshToken
+	if token == "" {
+		return rt, "", errmsg.ERROR_TOKEN_EXIST
+	}
+	// SELECT * FROM refresh_token WHERE token_hash = 'sha256' LIMIT 1;
+	db.Where("token_hash = ?", HashToken(token)).Limit(1).Find(&rt)
+	if rt.ID == 0 {
+		return rt, "", errmsg.ERROR_TOKEN_WRONG
+	}
+	if rt.Revoked {
+		return rt, "", errmsg.ERROR_TOKEN_REVOKED
+	}
+	if rt.UsedAt != nil {
+		RevokeTokenFamily(rt.Family)
+		return rt, "", errmsg.ERROR_TOKEN_REUSED
+	}
+	if time.Now().After(rt.ExpiresAt) {
+		return rt, "", errmsg.ERROR_TOKEN_RUNTIME
+	}
+
+	/**
+	-- 条件更新保证并发请求中只有一个能轮换成功
+	UPDATE refresh_token SET used_at = CURRENT_TIMESTAMP WHERE id = 5 AND used_at IS NULL;
+	*/
+	res := db.Model(&RefreshToken{}).Where("id = ? AND used_at IS NULL", rt.ID).Update("used_at", time.Now())
+	if res.Error != nil {
+		return rt, "", errmsg.ERROR
+	}
+	if res.RowsAffected == 0 {
+		RevokeTokenFamily(rt.Family)
+		return rt, "", errmsg.ERROR_TOKEN_REUSED
+	}
+
+	newToken, _, code := CreateRefreshToken(rt.UserId, rt.Family, rt.Scope)
+	return rt, newToken, code
+}
+
+// RevokeRefreshToken 吊销刷新令牌所在的整个家族
+func RevokeRefreshToken(token string) int {
+	var rt RefreshToken
+	db.Select("family").Where("token_hash = ?", HashToken(token)).Limit(1).Find(&rt)
+	if rt.Family == "" {
+		return errmsg.ERROR_TOKEN_WRONG
+	}
+	return RevokeTokenFamily(rt.Family)
+}
+
+// RevokeTokenFamily 吊销令牌家族，该家族签发的访问令牌同时失效
+func RevokeTokenFamily(family string) int {
+	// UPDATE refresh_token SET revoked = true WHERE family = 'family';
+	err = db.Model(&RefreshToken{}).Where("family = ?", family).Update("revoked", true).Error
+	if err != nil {
+		return errmsg.ERROR
+	}
+	// UPDATE session SET revoked = true WHERE family = 'family';
+	db.Model(&Session{}).Where("family = ?", family).Update("revoked", true)
+	return errmsg.SUCCESS
+}
+
+// RevokeUserTokens 吊销用户的全部登录，keepFamily 不为空时保留该登录
+func RevokeUserTokens(userId uint, keepFamily string) int {
+	/**
+	UPDATE refresh_token SET revoked = true
+	WHERE user_id = 5 AND family <> 'family';
+	*/
+	query := db.Model(&RefreshToken{}).Where("user_id = ?", userId)
+	sessions := db.Model(&Session{}).Where("user_id = ?", userId)
+	if keepFamily != "" {
+		query = query.Where("family <> ?", keepFamily)
+		sessions = sessions.Where("family <> ?", keepFamily)
+	}
+	err = query.Update("revoked", true).Error
+	if err != nil {
+		return errmsg.ERROR
+	}
+	sessions.Update("revoked", true)
+	return errmsg.SUCCESS
+}
+
+// RevokeAccessToken 拉黑访问令牌
+func RevokeAccessToken(jti string, expiresAt time.Time) int {
+	// 顺带清理已自然过期的黑名单记录
+	db.Where("expires_at < ?", time.Now()).Delete(&RevokedToken{})
+	err = db.Save(&RevokedToken{Jti: jti, ExpiresAt: expiresAt}).Error
+	if err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+// CheckTokenRevoked 检查访问令牌本身或其所属家族是否已被吊销
+func CheckTokenRevoked(jti string, family string) bool {
+	var total int64
+	// SELECT COUNT(*) FROM revoked_token WHERE jti = 'jti';
+	db.Model(&RevokedToken{}).Where("jti = ?", jti).Count(&total)
+	if total > 0 {
+		return true
+	}
+	if family == "" {
+		return false
+	}
+	// SELECT COUNT(*) FROM refresh_token WHERE family = 'family' AND revoked = true;
+	db.Model(&RefreshToken{}).Where("family = ? AND revoked = ?", family, true).Count(&total)
+	return total > 0
+}
@@ -0,0 +1,143 @@
+package model
+
+import (
+	"github.com/wejectchen/ginblog/utils/errmsg"
+	"github.com/wejectchen/ginblog/utils/totp"
+	"strings"
+	"time"
+)
+
+// TotpIssuer 身份验证器 App 中显示的名称
+const TotpIssuer = "GinBlog"
+
+// recoveryCodeCount 每次生成的恢复码数量
+const recoveryCodeCount = 10
+
+// RecoveryCode 两步验证恢复码，只保存摘要，一次有效
+type RecoveryCode struct {
+	ID       uint       `gorm:"primaryKey" json:"id"`
+	UserId   uint       `gorm:"index;not null" json:"user_id"`
+	CodeHash string     `gorm:"type:varchar(64);not null" json:"-"`
+	UsedAt   *time.Time `json:"used_at"`
+}
+
+// SetupTotp 生成待确认的 TOTP 密钥，确认前不会生效
+func SetupTotp(userId uint) (string, string, int) {
+	user, _ := GetUser(int(userId))
+	if user.ID == 0 {
+		return "", "", errmsg.ERROR_USER_NOT_EXIST
+	}
+	if user.TotpEnabled {
+		return "", "", errmsg.ERROR_MFA_ENABLED
+	}
+	secret := totp.GenerateSecret()
+	// UPDATE user SET totp_secret = 'BASE32' WHERE id = 5;
+	err = db.Model(&User{}).Where("id = ?", userId).Update("totp_secret", secret).Error
+	if err != nil {
+		return "", "", errmsg.ERROR
+	}
+	return secret, totp.URI(TotpIssuer, user.Username, secret), errmsg.SUCCESS
+}
+
+// ConfirmTotp 使用验证码确认并开启两步验证，返回新生成的恢复码
+func ConfirmTotp(userId uint, code string) ([]string, int) {
+	user, _ := GetUser(int(userId))
+	if user.ID == 0 {
+		return nil, errmsg.ERROR_USER_NOT_EXIST
+	}
+	if user.TotpEnabled {
+		return nil, errmsg.ERROR_MFA_ENABLED
+	}
+	if user.TotpSecret == "" {
+		return nil, errmsg.ERROR_MFA_NOT_ENABLED
+	}
+	counter, ok := totp.Validate(user.TotpSecret, code, time.Now())
+	if !ok {
+		return nil, errmsg.ERROR_MFA_CODE_WRONG
+	}
+	/**
+	UPDATE user SET totp_enabled = true, totp_counter = 56666666 WHERE id = 5;
+	*/
+	err = db.Model(&User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+		"totp_enabled": true,
+		"totp_counter": counter,
+	}).Error
+	if err != nil {
+		return nil, errmsg.ERROR
+	}
+	return RegenerateRecoveryCodes(userId)
+}
+
+// VerifyMfa 校验两步验证码，也接受一次性的恢复码
+func VerifyMfa(userId uint, code string) int {
+	user, _ := GetUser(int(userId))
+	if user.ID == 0 {
+		return errmsg.ERROR_USER_NOT_EXIST
+	}
+	if !user.TotpEnabled {
+		return errmsg.ERROR_MFA_NOT_ENABLED
+	}
+	code = strings.TrimSpace(code)
+	if counter, ok := totp.Validate(user.TotpSecret, code, time.Now()); ok {
+		/**
+		-- 同一时间步的验证码只能使用一次
+		UPDATE user SET totp_counter = 56666666 WHERE id = 5 AND totp_counter < 56666666;
+		*/
+		res := db.Model(&User{}).Where("id = ? AND totp_counter < ?", userId, counter).Update("totp_counter", counter)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return errmsg.ERROR_MFA_CODE_WRONG
+		}
+		return errmsg.SUCCESS
+	}
+	return useRecoveryCode(userId, code)
+}
+
+func useRecoveryCode(userId uint, code string) int {
+	/**
+	UPDATE recovery_code SET used_at = CURRENT_TIMESTAMP
+	WHERE user_id = 5 AND code_hash = 'sha256' AND used_at IS NULL;
+	*/
+	res := db.Model(&RecoveryCode{}).Where("user_id = ? AND code_hash = ? AND used_at IS NULL",
+		userId, HashToken(strings.ToLower(code))).Update("used_at", time.Now())
+	if res.Error != nil || res.RowsAffected == 0 {
+		return errmsg.ERROR_MFA_CODE_WRONG
+	}
+	return errmsg.SUCCESS
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧的恢复码全部作废
+func RegenerateRecoveryCodes(userId uint) ([]string, int) {
+	// DELETE FROM recovery_code WHERE user_id = 5;
+	db.Where("user_id = ?", userId).Delete(&RecoveryCode{})
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := RandomToken(5)
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		rows = append(rows, RecoveryCode{UserId: userId, CodeHash: HashToken(code)})
+	}
+	err = db.Create(&rows).Error
+	if err != nil {
+		return nil, errmsg.ERROR
+	}
+	return codes, errmsg.SUCCESS
+}
+
+// DisableTotp 关闭两步验证并删除恢复码
+func DisableTotp(userId uint) int {
+	/**
+	UPDATE user SET totp_enabled = false, totp_secret = '', totp_counter = 0 WHERE id = 5;
+	DELETE FROM recovery_code WHERE user_id = 5;
+	*/
+	err = db.Model(&User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+		"totp_enabled": false,
+		"totp_secret":  "",
+		"totp_counter": 0,
+	}).Error
+	if err != nil {
+		return errmsg.ERROR
+	}
+	db.Where("user_id = ?", userId).Delete(&RecoveryCode{})
+	return errmsg.SUCCESS
+}
@@ -5,13 +5,28 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"log"
+	"time"
+)
+
+// 账号状态
+const (
+	UserActive  = 1 // 正常
+	UserPending = 2 // 待验证邮箱
 )
 
 type User struct {
 	gorm.Model
 	Username string `gorm:"type:varchar(20);not null " json:"username" validate:"required,min=4,max=12" label:"用户名"`
 	Password string `gorm:"type:varchar(500);not null" json:"password" validate:"required,min=6,max=120" label:"密码"`
-	Role     int    `gorm:"type:int;DEFAULT:2" json:"role" validate:"required,gte=2" label:"角色码"`
+	Role     int    `gorm:"type:int;DEFAULT:2" json:"role" validate:"required,gte=1" label:"角色码"`
+	Email    string `gorm:"type:varchar(100);index" json:"email" validate:"omitempty,email,max=100" label:"邮箱"`
+	// 账号状态，前台注册的账号在验证邮箱前处于待验证状态
+	Status          int        `gorm:"type:int;not null;default:1" json:"status"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// 两步验证
+	TotpEnabled bool   `gorm:"not null;default:false" json:"totp_enabled"`
+	TotpSecret  string `gorm:"type:varchar(64)" json:"-"`
+	TotpCounter int64  `gorm:"not null;default:0" json:"-"`
 }
 
 // CheckUser 查询用户是否存在
@@ -87,7 +102,7 @@ func GetUsers(username string, pageSize int, pageNum int) ([]User, int64) {
 	SELECT COUNT(*) AS total FROM user WHERE username LIKE 'test%';
 	*/
 	if username != "" {
-		db.Select("id,username,role,created_at").Where(
+		db.Select("id,username,email,role,status,email_verified_at,created_at").Where(
 			"username LIKE ?", username+"%",
 		).Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&users)
 		db.Model(&users).Where(
@@ -104,7 +119,7 @@ func GetUsers(username string, pageSize int, pageNum int) ([]User, int64) {
 	-- 统计总条数
 	SELECT COUNT(*) AS total FROM users;
 	*/
-	db.Select("id,username,role,created_at").Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&users)
+	db.Select("id,username,email,role,status,email_verified_at,created_at").Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&users)
 	db.Model(&users).Count(&total)
 
 	if err != nil {
@@ -118,35 +133,54 @@ func EditUser(id int, data *User) int {
 	var user User
 	var maps = make(map[string]interface{})
 	maps["username"] = data.Username
-	maps["role"] = data.Role
+	if data.Role > 0 {
+		maps["role"] = data.Role
+	}
+	// 未提交邮箱时保留原邮箱，更换邮箱后需要重新验证
+	// SELECT email FROM user WHERE id = 5 LIMIT 1;
+	db.Select("email").Where("id = ?", id).Limit(1).Find(&user)
+	if data.Email != "" && data.Email != user.Email {
+		maps["email"] = data.Email
+		maps["email_verified_at"] = nil
+	}
 	/**
 	-- 假设更新 ID=5 的用户：username="updateduser"，role=1（管理员）
 	UPDATE user
 	SET username = 'updateduser', role = 1, updated_at = CURRENT_TIMESTAMP
 	WHERE id = 5;
 	*/
-	err = db.Model(&user).Where("id = ? ", id).Updates(maps).Error
+	err = db.Model(&User{}).Where("id = ? ", id).Updates(maps).Error
 	if err != nil {
 		return errmsg.ERROR
 	}
 	return errmsg.SUCCESS
 }
 
-// ChangePassword 修改密码
-func ChangePassword(id int, data *User) int {
-	//var user User
-	//var maps = make(map[string]interface{})
-	//maps["password"] = data.Password
+// ChangePassword 修改密码，并注销该用户除 keepFamily 外的所有登录
+func ChangePassword(id int, password string, keepFamily string) int {
 	/**
 	-- 假设更新 ID=5 的用户密码（新密码已加密）
 	UPDATE user
 	SET password = '$2a$10$新加密的密码', updated_at = CURRENT_TIMESTAMP
 	WHERE id = 5;
 	*/
-	err = db.Select("password").Where("id = ?", id).Updates(&data).Error
+	err = db.Model(&User{}).Where("id = ?", id).Update("password", ScryptPw(password)).Error
 	if err != nil {
 		return errmsg.ERROR
 	}
+	return RevokeUserTokens(uint(id), keepFamily)
+}
+
+// CheckPassword 校验用户当前密码
+func CheckPassword(id int, password string) int {
+	var user User
+	db.Select("id, password").Where("id = ?", id).Limit(1).Find(&user)
+	if user.ID == 0 {
+		return errmsg.ERROR_USER_NOT_EXIST
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return errmsg.ERROR_PASSWORD_WRONG
+	}
 	return errmsg.SUCCESS
 }
 
@@ -167,15 +201,13 @@ func DeleteUser(id int) int {
 	return errmsg.SUCCESS
 }
 
-// BeforeCreate 密码加密&权限控制
+// BeforeCreate 密码加密，未指定角色时默认为订阅者
+// 前台注册的角色由接口层强制指定，这里不再覆盖管理员分配的角色
 func (u *User) BeforeCreate(_ *gorm.DB) (err error) {
 	u.Password = ScryptPw(u.Password)
-	u.Role = 2
-	return nil
-}
-
-func (u *User) BeforeUpdate(_ *gorm.DB) (err error) {
-	u.Password = ScryptPw(u.Password)
+	if u.Role == 0 {
+		u.Role = RoleReader
+	}
 	return nil
 }
 
@@ -191,6 +223,17 @@ func ScryptPw(password string) string {
 	return string(HashPw)
 }
 
+// dummyHash 用户不存在时也执行一次 bcrypt 比对，使响应时间与密码错误时一致
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("ginblog"), 10)
+
+func comparePassword(user User, password string) error {
+	if user.ID == 0 {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return bcrypt.ErrMismatchedHashAndPassword
+	}
+	return bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+}
+
 // CheckLogin 后台登录验证
 func CheckLogin(username string, password string) (User, int) {
 	var user User
@@ -202,17 +245,22 @@ func CheckLogin(username string, password string) (User, int) {
 	*/
 	db.Where("username = ?", username).First(&user)
 
-	PasswordErr = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	PasswordErr = comparePassword(user, password)
 
-	if user.ID == 0 {
-		return user, errmsg.ERROR_USER_NOT_EXIST
-	}
-	if PasswordErr != nil {
-		return user, errmsg.ERROR_PASSWORD_WRONG
+	// 用户不存在与密码错误返回同样的结果，避免被用来探测用户名
+	if user.ID == 0 || PasswordErr != nil {
+		return User{}, errmsg.ERROR_LOGIN_INVALID
 	}
-	if user.Role != 1 {
+	if !HasPermission(user.Role, PermAdminAccess) {
 		return user, errmsg.ERROR_USER_NO_RIGHT
 	}
+	// 密码正确后还需要完成两步验证
+	if user.TotpEnabled {
+		return user, errmsg.ERROR_MFA_REQUIRED
+	}
+	if user.Role == RoleAdmin && GetSettingBool(SettingRequireAdminMfa) {
+		return user, errmsg.ERROR_MFA_ENROLL_REQUIRED
+	}
 	return user, errmsg.SUCCESS
 }
 
@@ -223,12 +271,12 @@ func CheckLoginFront(username string, password string) (User, int) {
 
 	db.Where("username = ?", username).First(&user)
 
-	PasswordErr = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-	if user.ID == 0 {
-		return user, errmsg.ERROR_USER_NOT_EXIST
+	PasswordErr = comparePassword(user, password)
+	if user.ID == 0 || PasswordErr != nil {
+		return User{}, errmsg.ERROR_LOGIN_INVALID
 	}
-	if PasswordErr != nil {
-		return user, errmsg.ERROR_PASSWORD_WRONG
+	if user.Status == UserPending && GetSettingBool(SettingRequireEmailVerify) {
+		return user, errmsg.ERROR_EMAIL_NOT_VERIFIED
 	}
 	return user, errmsg.SUCCESS
 }
@@ -41,9 +41,18 @@ func InitDb() {
 		os.Exit(1)
 	}
 
+	// 补全升级前数据的 slug，需要在建立唯一索引之前执行
+	initSlugs()
 	// 迁移数据表，在没有数据表结构变更时候，建议注释不执行 会根据当前的结构体改变mysql中的表结构
 	// 注意:初次运行后可注销此行
-	_ = db.AutoMigrate(&User{}, &Article{}, &Category{}, Profile{}, Comment{})
+	_ = db.AutoMigrate(&User{}, &Article{}, &Category{}, Profile{}, Comment{}, RefreshToken{}, RevokedToken{}, Role{}, Permission{}, PasswordReset{}, EmailVerify{}, Setting{}, RecoveryCode{}, UserIdentity{}, ApiKey{}, Session{}, AuditLog{}, ArticleRevision{}, Tag{}, SlugRedirect{})
+	InitRbac()
+	initArticleStatus()
+	initContentHtml()
+	initContentText()
+	initCommentContent()
+	initSearch()
+	initSpam()
 
 	sqlDB, _ := db.DB()
 	// SetMaxIdleCons 设置连接池中的最大闲置连接数。
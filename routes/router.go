@@ -5,6 +5,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/wejectchen/ginblog/api/v1"
 	"github.com/wejectchen/ginblog/middleware"
+	"github.com/wejectchen/ginblog/model"
 	"github.com/wejectchen/ginblog/utils"
 	"strings"
 )
@@ -27,6 +28,7 @@ func InitRouter() {
 	r.Use(middleware.Logger())
 	r.Use(gin.Recovery())
 	r.Use(middleware.Cors())
+	r.Use(middleware.Captcha())
 
 	r.Static("/static", "./web/front/dist/static")
 	r.Static("/admin", "./web/admin/dist")
@@ -40,39 +42,112 @@ func InitRouter() {
 		c.HTML(200, "admin", nil)
 	})
 
+	// 验签公钥
+	r.GET("/.well-known/jwks.json", v1.GetJwks)
+
 	/*
 		后台管理路由接口
 	*/
 	auth := r.Group("api/v1")
-	auth.Use(middleware.JwtToken())
+	auth.Use(middleware.JwtToken(), middleware.Require(model.PermAdminAccess), middleware.Audit())
 	{
 		// 用户模块的路由接口
-		auth.GET("admin/users", v1.GetUsers)
+		auth.GET("admin/users", middleware.Require(model.PermUserManage), v1.GetUsers)
+		auth.POST("admin/user/add", middleware.Require(model.PermUserManage), v1.AdminAddUser)
 		auth.PUT("user/:id", v1.EditUser)
 		auth.DELETE("user/:id", v1.DeleteUser)
 		//修改密码
-		auth.PUT("admin/changepw/:id", v1.ChangeUserPassword)
+		auth.PUT("admin/changepw/:id", middleware.LoginOnly(), v1.ChangeUserPassword)
+		// 两步验证
+		auth.POST("user/totp/setup", middleware.LoginOnly(), v1.TotpSetup)
+		auth.POST("user/totp/confirm", middleware.LoginOnly(), v1.TotpConfirm)
+		auth.POST("user/totp/disable", middleware.LoginOnly(), v1.TotpDisable)
+		auth.POST("user/totp/recovery", middleware.LoginOnly(), v1.TotpRecoveryCodes)
+		auth.DELETE("admin/user/:id/totp", middleware.Require(model.PermUserManage), v1.ResetUserTotp)
+		auth.DELETE("admin/user/:id/lock", middleware.Require(model.PermUserManage), v1.UnlockUser)
+		// 登录会话
+		auth.GET("admin/user/:id/sessions", middleware.Require(model.PermUserManage), v1.GetUserSessions)
+		auth.DELETE("admin/user/:id/sessions", middleware.Require(model.PermUserManage), middleware.LoginOnly(), v1.DeleteUserSessions)
+		auth.DELETE("admin/session/:id", middleware.Require(model.PermUserManage), middleware.LoginOnly(), v1.AdminDeleteSession)
+		// API 密钥
+		auth.GET("admin/apikeys", middleware.LoginOnly(), v1.GetApiKeys)
+		auth.POST("admin/apikey/add", middleware.LoginOnly(), v1.AddApiKey)
+		auth.DELETE("admin/apikey/:id", middleware.LoginOnly(), v1.DeleteApiKey)
+		// 角色权限模块
+		auth.GET("admin/roles", middleware.Require(model.PermUserManage), v1.GetRoles)
+		auth.GET("admin/permissions", middleware.Require(model.PermUserManage), v1.GetPermissions)
+		auth.PUT("admin/role/:id", middleware.Require(model.PermUserManage), v1.EditRole)
+		// 审计日志
+		auth.GET("admin/audit", middleware.Require(model.PermAuditRead), v1.GetAuditLogs)
+		auth.GET("admin/audit/export", middleware.Require(model.PermAuditRead), v1.ExportAuditLogs)
+		// 站点设置
+		auth.GET("admin/settings", middleware.Require(model.PermSettingManage), v1.GetSettings)
+		auth.PUT("admin/settings", middleware.Require(model.PermSettingManage), v1.UpdateSettings)
+		auth.POST("admin/keys/rotate", middleware.Require(model.PermSettingManage), middleware.LoginOnly(), v1.RotateKeys)
 		// 分类模块的路由接口
 		auth.GET("admin/category", v1.GetCate)
-		auth.POST("category/add", v1.AddCategory)
-		auth.PUT("category/:id", v1.EditCate)
-		auth.DELETE("category/:id", v1.DeleteCate)
+		auth.POST("category/add", middleware.Require(model.PermCategoryManage), v1.AddCategory)
+		auth.PUT("category/:id", middleware.Require(model.PermCategoryManage), v1.EditCate)
+		auth.DELETE("category/:id", middleware.Require(model.PermCategoryManage), v1.DeleteCate)
+		// 标签模块的路由接口
+		auth.GET("admin/tag", v1.GetTags)
+		auth.POST("tag/add", middleware.Require(model.PermCategoryManage), v1.AddTag)
+		auth.POST("tag/merge", middleware.Require(model.PermCategoryManage), v1.MergeTags)
+		auth.PUT("tag/:id", middleware.Require(model.PermCategoryManage), v1.EditTag)
+		auth.DELETE("tag/:id", middleware.Require(model.PermCategoryManage), v1.DeleteTag)
 		// 文章模块的路由接口
-		auth.GET("admin/article/info/:id", v1.GetArtInfo)
-		auth.GET("admin/article", v1.GetArt)
-		auth.POST("article/add", v1.AddArticle)
-		auth.PUT("article/:id", v1.EditArt)
-		auth.DELETE("article/:id", v1.DeleteArt)
+		auth.GET("admin/article/info/:id", v1.GetArtInfoAdmin)
+		auth.GET("admin/article", v1.GetArtAdmin)
+		auth.POST("article/add", middleware.Require(model.PermArticleWrite), v1.AddArticle)
+		auth.PUT("article/:id", middleware.Require(model.PermArticleWrite), v1.EditArt)
+		auth.DELETE("article/:id", middleware.Require(model.PermArticleWrite), v1.DeleteArt)
+		auth.PUT("article/:id/status", middleware.Require(model.PermArticleWrite), v1.SetArtStatus)
+		auth.POST("admin/markdown/preview", middleware.Require(model.PermArticleWrite), v1.PreviewMarkdown)
+		// 文章修订版本
+		auth.GET("admin/article/:id/revisions", middleware.Require(model.PermArticleWrite), v1.GetRevisions)
+		auth.GET("admin/article/:id/revision/:version", middleware.Require(model.PermArticleWrite), v1.GetRevision)
+		auth.GET("admin/article/:id/diff", middleware.Require(model.PermArticleWrite), v1.DiffRevisions)
+		auth.POST("article/:id/revision/:version/restore", middleware.Require(model.PermArticleWrite), v1.RestoreRevision)
 		// 上传文件
-		auth.POST("upload", v1.UpLoad)
+		auth.POST("upload", middleware.Require(model.PermFileUpload), v1.UpLoad)
 		// 更新个人设置
 		auth.GET("admin/profile/:id", v1.GetProfile)
 		auth.PUT("profile/:id", v1.UpdateProfile)
 		// 评论模块
-		auth.GET("comment/list", v1.GetCommentList)
+		auth.GET("comment/list", middleware.Require(model.PermCommentModerate), v1.GetCommentList)
+		auth.GET("admin/comment/info/:id", middleware.Require(model.PermCommentModerate), v1.GetCommentAdmin)
 		auth.DELETE("delcomment/:id", v1.DeleteComment)
-		auth.PUT("checkcomment/:id", v1.CheckComment)
-		auth.PUT("uncheckcomment/:id", v1.UncheckComment)
+		auth.PUT("checkcomment/:id", middleware.Require(model.PermCommentModerate), v1.CheckComment)
+		auth.PUT("uncheckcomment/:id", middleware.Require(model.PermCommentModerate), v1.UncheckComment)
+		auth.PUT("spamcomment/:id", middleware.Require(model.PermCommentModerate), v1.SpamComment)
+		auth.POST("admin/spam/retrain", middleware.Require(model.PermCommentModerate), v1.RetrainSpam)
+	}
+
+	/*
+		前台读者登录后可用的接口
+	*/
+	reader := r.Group("api/v1")
+	reader.Use(middleware.JwtReader())
+	{
+		reader.PUT("user/password", middleware.LoginOnly(), v1.ChangeOwnPassword)
+		// 第三方账号绑定
+		reader.POST("oauth/:provider/link", middleware.LoginOnly(), v1.OAuthLink)
+		reader.GET("user/identities", v1.GetUserIdentities)
+		reader.DELETE("user/identity/:id", middleware.LoginOnly(), v1.DeleteUserIdentity)
+		// 登录会话
+		reader.GET("user/sessions", middleware.LoginOnly(), v1.GetSessions)
+		reader.DELETE("user/sessions", middleware.LoginOnly(), v1.DeleteOtherSessions)
+		reader.DELETE("user/session/:id", middleware.LoginOnly(), v1.DeleteSession)
+	}
+
+	/*
+		游客与登录用户均可使用的接口
+	*/
+	guest := r.Group("api/v1")
+	guest.Use(middleware.JwtOptional())
+	{
+		guest.POST("addcomment", v1.AddComment)
+		guest.POST("comment/:id/reply", v1.ReplyComment)
 	}
 
 	/*
@@ -83,29 +158,52 @@ func InitRouter() {
 		// 用户信息模块
 		router.POST("user/add", v1.AddUser)
 		router.GET("user/:id", v1.GetUserInfo)
-		router.GET("users", v1.GetUsers)
 
 		// 文章分类信息模块
 		router.GET("category", v1.GetCate)
 		router.GET("category/:id", v1.GetCateInfo)
+		router.GET("category/slug/:slug", v1.GetCateInfoBySlug)
+		router.GET("category/slug/:slug/articles", v1.GetCateArtBySlug)
+		router.GET("tag", v1.GetTagCloud)
+		router.GET("tag/:slug/articles", v1.GetTagArt)
 
 		// 文章模块
 		router.GET("article", v1.GetArt)
 		router.GET("article/list/:id", v1.GetCateArt)
 		router.GET("article/info/:id", v1.GetArtInfo)
+		router.GET("article/slug/:slug", v1.GetArtInfoBySlug)
+		router.GET("search", v1.SearchArticle)
+		router.GET("search/suggest", v1.SuggestSearch)
+
+		// 验证码
+		router.GET("captcha", v1.GetCaptcha)
+		router.POST("captcha/verify", v1.VerifyCaptcha)
 
 		// 登录控制模块
 		router.POST("login", v1.Login)
+		router.POST("login/mfa", v1.LoginMfa)
+		router.POST("login/mfa/setup", v1.LoginMfaSetup)
+		router.POST("login/mfa/enroll", v1.LoginMfaEnroll)
 		router.POST("loginfront", v1.LoginFront)
+		router.POST("token/refresh", v1.RefreshToken)
+		router.POST("logout", v1.Logout)
+		router.POST("password/forgot", v1.ForgotPassword)
+		router.POST("password/reset", v1.ResetPassword)
+		router.POST("user/verify", v1.VerifyEmail)
+		router.POST("user/verify/resend", v1.ResendVerifyEmail)
+		// 第三方登录
+		router.GET("oauth/providers", v1.GetOAuthProviders)
+		router.GET("oauth/:provider/login", v1.OAuthLogin)
+		router.GET("oauth/:provider/callback", v1.OAuthCallback)
 
 		// 获取个人设置信息
 		router.GET("profile/:id", v1.GetProfile)
 
 		// 评论模块
-		router.POST("addcomment", v1.AddComment)
 		router.GET("comment/info/:id", v1.GetComment)
 		router.GET("commentfront/:id", v1.GetCommentListFront)
 		router.GET("commentcount/:id", v1.GetCommentCount)
+		router.GET("comment/config", v1.GetCommentConfig)
 	}
 
 	// 关键：添加兜底路由，处理前端所有路由路径
@@ -0,0 +1,108 @@
+package captcha
+
+import (
+	"errors"
+	"github.com/wejectchen/ginblog/utils"
+	"github.com/wejectchen/ginblog/utils/store"
+	"strings"
+)
+
+// 验证码类型
+const (
+	TypeImage = "image" // 图片算术题
+	TypePow   = "pow"   // 工作量证明，由客户端计算，无需用户交互
+	// typeTicket 预先校验通过后换取的一次性凭证
+	typeTicket = "ticket"
+)
+
+var ErrType = errors.New("不支持的验证码类型")
+
+// Challenge 下发给客户端的验证码
+type Challenge struct {
+	Id   string `json:"id"`
+	Type string `json:"type"`
+	// Image 图片验证码，data URI 格式
+	Image string `json:"image,omitempty"`
+	// Prefix 与 Difficulty 用于工作量证明：
+	// 找到 nonce 使 sha256(prefix + nonce) 的前 Difficulty 个比特为 0
+	Prefix     string `json:"prefix,omitempty"`
+	Difficulty int    `json:"difficulty,omitempty"`
+	ExpiresIn  int    `json:"expires_in"`
+}
+
+func key(id string) string {
+	return "captcha:" + id
+}
+
+// New 生成验证码，答案保存在存储中，有效期由 [captcha] Expire 决定
+func New(kind string) (Challenge, error) {
+	if kind == "" {
+		kind = utils.CaptchaType
+	}
+	ch := Challenge{
+		Id:        randomId(),
+		Type:      kind,
+		ExpiresIn: int(utils.CaptchaExpire.Seconds()),
+	}
+	var answer string
+	var err error
+	switch kind {
+	case TypeImage:
+		ch.Image, answer, err = newImage()
+	case TypePow:
+		ch.Prefix, ch.Difficulty = randomId(), utils.CaptchaPowDifficulty
+		answer = ch.Prefix + ":" + itoa(ch.Difficulty)
+	default:
+		return ch, ErrType
+	}
+	if err != nil {
+		return ch, err
+	}
+	return ch, store.Default.Set(key(ch.Id), kind+":"+answer, utils.CaptchaExpire)
+}
+
+// Verify 校验答案，无论对错验证码都会作废，防止反复尝试
+func Verify(id string, answer string) bool {
+	if id == "" || answer == "" {
+		return false
+	}
+	value, err := store.Default.Take(key(id))
+	if err != nil || value == "" {
+		return false
+	}
+	kind, expect, _ := strings.Cut(value, ":")
+	switch kind {
+	case TypeImage:
+		return strings.TrimSpace(answer) == expect
+	case TypePow:
+		prefix, bits, _ := strings.Cut(expect, ":")
+		return checkPow(prefix, answer, atoi(bits))
+	case typeTicket:
+		return answer == expect
+	}
+	return false
+}
+
+// Routes 需要验证码的接口，格式统一为 方法:路径
+func Routes() []string {
+	routes := []string{}
+	for _, route := range utils.CaptchaRoutes {
+		method, path, ok := strings.Cut(strings.TrimSpace(route), ":")
+		if ok {
+			routes = append(routes, strings.ToUpper(method)+":"+path)
+		}
+	}
+	return routes
+}
+
+// Exchange 校验答案并换取一次性凭证，便于前端先校验再提交表单
+func Exchange(id string, answer string) (string, string, bool) {
+	if !Verify(id, answer) {
+		return "", "", false
+	}
+	ticket, secret := randomId(), randomId()
+	if err := store.Default.Set(key(ticket), typeTicket+":"+secret, utils.CaptchaExpire); err != nil {
+		return "", "", false
+	}
+	return ticket, secret, true
+}
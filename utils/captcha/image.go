@@ -0,0 +1,139 @@
+package captcha
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 5x7 点阵字体，只包含算术题用到的字符
+var glyphs = map[rune][7]string{
+	'0': {".###.", "#...#", "#..##", "#.#.#", "##..#", "#...#", ".###."},
+	'1': {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'2': {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	'3': {"#####", "...#.", "..#..", "...#.", "....#", "#...#", ".###."},
+	'4': {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'5': {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	'6': {"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	'7': {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'8': {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+	'9': {".###.", "#...#", "#...#", ".####", "....#", "...#.", ".##.."},
+	'+': {".....", "..#..", "..#..", "#####", "..#..", "..#..", "....."},
+	'-': {".....", ".....", ".....", "#####", ".....", ".....", "....."},
+	'=': {".....", ".....", "#####", ".....", "#####", ".....", "....."},
+	'?': {".###.", "#...#", "....#", "...#.", "..#..", ".....", "..#.."},
+}
+
+const (
+	imageWidth  = 170
+	imageHeight = 50
+	dotSize     = 3
+)
+
+var (
+	rnd   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	rndMu sync.Mutex
+)
+
+// newImage 生成算术题图片，返回 data URI 与答案
+func newImage() (string, string, error) {
+	rndMu.Lock()
+	defer rndMu.Unlock()
+
+	a, b := rnd.Intn(20)+1, rnd.Intn(10)+1
+	op, answer := '+', a+b
+	if rnd.Intn(2) == 0 && a >= b {
+		op, answer = '-', a-b
+	}
+	text := strconv.Itoa(a) + string(op) + strconv.Itoa(b) + "=?"
+
+	img := image.NewRGBA(image.Rect(0, 0, imageWidth, imageHeight))
+	bg := color.RGBA{R: uint8(230 + rnd.Intn(25)), G: uint8(230 + rnd.Intn(25)), B: uint8(230 + rnd.Intn(25)), A: 255}
+	for y := 0; y < imageHeight; y++ {
+		for x := 0; x < imageWidth; x++ {
+			img.Set(x, y, bg)
+		}
+	}
+
+	// 干扰线
+	for i := 0; i < 4; i++ {
+		drawLine(img, rnd.Intn(imageWidth), rnd.Intn(imageHeight), rnd.Intn(imageWidth), rnd.Intn(imageHeight), randomColor())
+	}
+
+	// 字符逐个随机偏移，增加识别难度
+	x := 8 + rnd.Intn(6)
+	for _, ch := range text {
+		glyph := glyphs[ch]
+		fg := randomColor()
+		y := 8 + rnd.Intn(imageHeight-7*dotSize-14)
+		for row, line := range glyph {
+			for col, dot := range line {
+				if dot != '#' {
+					continue
+				}
+				for dy := 0; dy < dotSize; dy++ {
+					for dx := 0; dx < dotSize; dx++ {
+						img.Set(x+col*dotSize+dx+row/3, y+row*dotSize+dy, fg)
+					}
+				}
+			}
+		}
+		x += 5*dotSize + 3 + rnd.Intn(4)
+	}
+
+	// 噪点
+	for i := 0; i < imageWidth*imageHeight/12; i++ {
+		img.Set(rnd.Intn(imageWidth), rnd.Intn(imageHeight), randomColor())
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), strconv.Itoa(answer), nil
+}
+
+func randomColor() color.RGBA {
+	return color.RGBA{R: uint8(rnd.Intn(150)), G: uint8(rnd.Intn(150)), B: uint8(rnd.Intn(150)), A: 255}
+}
+
+// drawLine Bresenham 画线
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		img.Set(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
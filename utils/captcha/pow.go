@@ -0,0 +1,51 @@
+package captcha
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/bits"
+	"strconv"
+)
+
+// checkPow 校验 sha256(prefix + nonce) 是否有足够多的前导零比特
+func checkPow(prefix string, nonce string, difficulty int) bool {
+	if len(nonce) > 64 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(prefix + nonce))
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			zeros += bits.LeadingZeros8(b)
+			break
+		}
+		zeros += 8
+	}
+	return zeros >= difficulty
+}
+
+// Solve 计算工作量证明，供脚本客户端与自测使用
+func Solve(prefix string, difficulty int) string {
+	for i := 0; ; i++ {
+		nonce := strconv.Itoa(i)
+		if checkPow(prefix, nonce, difficulty) {
+			return nonce
+		}
+	}
+}
+
+func randomId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func itoa(n int) string {
+	return strconv.Itoa(n)
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
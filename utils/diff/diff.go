@@ -0,0 +1,223 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op 行的编辑类型
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// Line 比较结果中的一行
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// maxEdits 编辑距离上限，超过后整体视为替换，避免超大文本占用过多内存
+const maxEdits = 2000
+
+// SplitLines 按行拆分文本，统一换行符，末尾换行不产生空行
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Lines 基于 Myers 算法逐行比较 a 与 b
+func Lines(a, b []string) []Line {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	out := make([]Line, 0, len(a)+len(b)-pre-suf)
+	for _, s := range a[:pre] {
+		out = append(out, Line{Equal, s})
+	}
+	out = append(out, myers(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	for _, s := range a[len(a)-suf:] {
+		out = append(out, Line{Equal, s})
+	}
+	return out
+}
+
+// myers 求最短编辑脚本，trace 只保存每一轮 [-d, d] 范围内的结果
+func myers(a, b []string) []Line {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+
+	for d := 0; d <= n+m; d++ {
+		if d > maxEdits {
+			return replace(a, b)
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+				return backtrack(trace, a, b)
+			}
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+	return replace(a, b)
+}
+
+// backtrack 从终点沿 trace 回溯出编辑脚本
+func backtrack(trace [][]int, a, b []string) []Line {
+	x, y := len(a), len(b)
+	var rev []Line
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			rev = append(rev, Line{Equal, a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			rev = append(rev, Line{Insert, b[y-1]})
+			y--
+		} else {
+			rev = append(rev, Line{Delete, a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		rev = append(rev, Line{Equal, a[x-1]})
+		x--
+		y--
+	}
+
+	out := make([]Line, len(rev))
+	for i, l := range rev {
+		out[len(rev)-1-i] = l
+	}
+	return out
+}
+
+func replace(a, b []string) []Line {
+	out := make([]Line, 0, len(a)+len(b))
+	for _, s := range a {
+		out = append(out, Line{Delete, s})
+	}
+	for _, s := range b {
+		out = append(out, Line{Insert, s})
+	}
+	return out
+}
+
+// Unified 生成与 diff -u 相同格式的差异，context 为每处修改前后保留的上下文行数
+// 内容相同时返回空字符串
+func Unified(fromName, toName, a, b string, context int) string {
+	lines := Lines(SplitLines(a), SplitLines(b))
+	// aPos、bPos 记录每一行之前两边各有多少行，用于计算块头的行号
+	aPos := make([]int, len(lines)+1)
+	bPos := make([]int, len(lines)+1)
+	changed := false
+	for i, l := range lines {
+		aPos[i+1], bPos[i+1] = aPos[i], bPos[i]
+		if l.Op != Insert {
+			aPos[i+1]++
+		}
+		if l.Op != Delete {
+			bPos[i+1]++
+		}
+		if l.Op != Equal {
+			changed = true
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", fromName, toName)
+	for i := 0; i < len(lines); {
+		for i < len(lines) && lines[i].Op == Equal {
+			i++
+		}
+		if i == len(lines) {
+			break
+		}
+		// 相邻修改之间的相同行不超过 2*context 时合并为一块
+		last := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].Op != Equal {
+				last = j
+			} else if j-last > 2*context {
+				break
+			}
+		}
+		start, end := i-context, last+context+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n",
+			hunkRange(aPos[start], aPos[end]-aPos[start]), hunkRange(bPos[start], bPos[end]-bPos[start]))
+		for _, l := range lines[start:end] {
+			switch l.Op {
+			case Equal:
+				buf.WriteByte(' ')
+			case Delete:
+				buf.WriteByte('-')
+			case Insert:
+				buf.WriteByte('+')
+			}
+			buf.WriteString(l.Text)
+			buf.WriteByte('\n')
+		}
+		i = end
+	}
+	return buf.String()
+}
+
+// hunkRange 块头中的行范围，行数为 0 时起始行号指向前一行
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
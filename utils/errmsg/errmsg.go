@@ -1,41 +1,123 @@
 package errmsg
 
 const (
-	SUCCESS = 200
-	ERROR   = 500
+	SUCCESS         = 200
+	ERROR_FORBIDDEN = 403
+	ERROR           = 500
 
 	// 用户模块的错误
-	ERROR_USERNAME_USED    = 1001
-	ERROR_PASSWORD_WRONG   = 1002
-	ERROR_USER_NOT_EXIST   = 1003
-	ERROR_TOKEN_EXIST      = 1004
-	ERROR_TOKEN_RUNTIME    = 1005
-	ERROR_TOKEN_WRONG      = 1006
-	ERROR_TOKEN_TYPE_WRONG = 1007
-	ERROR_USER_NO_RIGHT    = 1008
+	ERROR_USERNAME_USED       = 1001
+	ERROR_PASSWORD_WRONG      = 1002
+	ERROR_USER_NOT_EXIST      = 1003
+	ERROR_TOKEN_EXIST         = 1004
+	ERROR_TOKEN_RUNTIME       = 1005
+	ERROR_TOKEN_WRONG         = 1006
+	ERROR_TOKEN_TYPE_WRONG    = 1007
+	ERROR_USER_NO_RIGHT       = 1008
+	ERROR_TOKEN_REVOKED       = 1009
+	ERROR_TOKEN_REUSED        = 1010
+	ERROR_ROLE_NOT_EXIST      = 1011
+	ERROR_ROLE_LOCKED         = 1012
+	ERROR_RESET_TOKEN_INVALID = 1013
+	ERROR_MFA_REQUIRED        = 1014
+	ERROR_MFA_ENROLL_REQUIRED = 1015
+	ERROR_MFA_CODE_WRONG      = 1016
+	ERROR_MFA_ENABLED         = 1017
+	ERROR_MFA_NOT_ENABLED     = 1018
+	ERROR_LOGIN_INVALID       = 1019
+	ERROR_LOGIN_LOCKED        = 1020
+	ERROR_OAUTH_PROVIDER      = 1021
+	ERROR_OAUTH_STATE         = 1022
+	ERROR_OAUTH_FAILED        = 1023
+	ERROR_IDENTITY_LINKED     = 1024
+	ERROR_APIKEY_INVALID      = 1025
+	ERROR_APIKEY_SCOPE        = 1026
+	ERROR_APIKEY_FORBIDDEN    = 1027
+	ERROR_EMAIL_USED          = 1028
+	ERROR_EMAIL_NOT_VERIFIED  = 1029
+	ERROR_VERIFY_INVALID      = 1030
+	ERROR_VERIFY_THROTTLED    = 1031
+	ERROR_CAPTCHA_WRONG       = 1032
+	ERROR_SESSION_NOT_EXIST   = 1033
 	// 文章模块的错误
 	ERROR_ART_NOT_EXIST = 2001
+	ERROR_ART_STATUS    = 2002
+	ERROR_ART_SCHEDULE  = 2003
+	// 文章修订版本
+	ERROR_REVISION_NOT_EXIST = 2004
+	// 文章格式（Markdown/HTML）不支持
+	ERROR_ART_FORMAT = 2005
 	// 分类模块的错误
 	ERROR_CATENAME_USED  = 3001
 	ERROR_CATE_NOT_EXIST = 3002
+	// 标签模块的错误
+	ERROR_TAGNAME_USED  = 4001
+	ERROR_TAG_NOT_EXIST = 4002
+	// 评论模块的错误
+	ERROR_COMMENT_EMPTY     = 5001
+	ERROR_COMMENT_TOO_LONG  = 5002
+	ERROR_COMMENT_NOT_EXIST = 5003
+	ERROR_COMMENT_GUEST_OFF = 5004
+	ERROR_COMMENT_NAME_USED = 5005
+	ERROR_COMMENT_THROTTLED = 5006
 )
 
 var codeMsg = map[int]string{
-	SUCCESS:                "OK",
-	ERROR:                  "FAIL",
-	ERROR_USERNAME_USED:    "用户名已存在！",
-	ERROR_PASSWORD_WRONG:   "密码错误",
-	ERROR_USER_NOT_EXIST:   "用户不存在",
-	ERROR_TOKEN_EXIST:      "TOKEN不存在,请重新登陆",
-	ERROR_TOKEN_RUNTIME:    "TOKEN已过期,请重新登陆",
-	ERROR_TOKEN_WRONG:      "TOKEN不正确,请重新登陆",
-	ERROR_TOKEN_TYPE_WRONG: "TOKEN格式错误,请重新登陆",
-	ERROR_USER_NO_RIGHT:    "该用户无权限",
-
-	ERROR_ART_NOT_EXIST: "文章不存在",
+	SUCCESS:                   "OK",
+	ERROR_FORBIDDEN:           "无权操作该资源",
+	ERROR:                     "FAIL",
+	ERROR_USERNAME_USED:       "用户名已存在！",
+	ERROR_PASSWORD_WRONG:      "密码错误",
+	ERROR_USER_NOT_EXIST:      "用户不存在",
+	ERROR_TOKEN_EXIST:         "TOKEN不存在,请重新登陆",
+	ERROR_TOKEN_RUNTIME:       "TOKEN已过期,请重新登陆",
+	ERROR_TOKEN_WRONG:         "TOKEN不正确,请重新登陆",
+	ERROR_TOKEN_TYPE_WRONG:    "TOKEN格式错误,请重新登陆",
+	ERROR_USER_NO_RIGHT:       "该用户无权限",
+	ERROR_TOKEN_REVOKED:       "TOKEN已失效,请重新登陆",
+	ERROR_TOKEN_REUSED:        "刷新令牌被重复使用,该登录已被注销",
+	ERROR_ROLE_NOT_EXIST:      "角色不存在",
+	ERROR_ROLE_LOCKED:         "内置管理员角色不可修改",
+	ERROR_RESET_TOKEN_INVALID: "重置链接无效或已过期",
+	ERROR_MFA_REQUIRED:        "请输入两步验证码",
+	ERROR_MFA_ENROLL_REQUIRED: "管理员账号必须先开启两步验证",
+	ERROR_MFA_CODE_WRONG:      "两步验证码错误",
+	ERROR_MFA_ENABLED:         "已开启两步验证",
+	ERROR_MFA_NOT_ENABLED:     "未开启两步验证",
+	ERROR_LOGIN_INVALID:       "用户名或密码错误",
+	ERROR_LOGIN_LOCKED:        "登录失败次数过多,请稍后再试",
+	ERROR_OAUTH_PROVIDER:      "不支持该第三方登录方式",
+	ERROR_OAUTH_STATE:         "登录请求已失效,请重新登录",
+	ERROR_OAUTH_FAILED:        "第三方登录失败",
+	ERROR_IDENTITY_LINKED:     "该第三方账号已绑定其他用户",
+	ERROR_APIKEY_INVALID:      "API密钥无效或已过期",
+	ERROR_APIKEY_SCOPE:        "API密钥权限范围或有效期无效",
+	ERROR_APIKEY_FORBIDDEN:    "该操作需要登录,不能使用API密钥",
+	ERROR_EMAIL_USED:          "邮箱已被使用",
+	ERROR_EMAIL_NOT_VERIFIED:  "邮箱尚未验证,请先查收验证邮件",
+	ERROR_VERIFY_INVALID:      "验证链接无效或已过期",
+	ERROR_VERIFY_THROTTLED:    "发送过于频繁,请稍后再试",
+	ERROR_CAPTCHA_WRONG:       "验证码错误或已过期",
+	ERROR_SESSION_NOT_EXIST:   "会话不存在",
+
+	ERROR_ART_NOT_EXIST:      "文章不存在",
+	ERROR_ART_STATUS:         "文章状态不正确",
+	ERROR_ART_SCHEDULE:       "定时发布时间必须晚于当前时间",
+	ERROR_REVISION_NOT_EXIST: "文章版本不存在",
+	ERROR_ART_FORMAT:         "文章格式不正确",
 
 	ERROR_CATENAME_USED:  "该分类已存在",
 	ERROR_CATE_NOT_EXIST: "该分类不存在",
+
+	ERROR_TAGNAME_USED:  "该标签已存在",
+	ERROR_TAG_NOT_EXIST: "该标签不存在",
+
+	ERROR_COMMENT_EMPTY:     "评论内容不能为空",
+	ERROR_COMMENT_TOO_LONG:  "评论内容过长",
+	ERROR_COMMENT_NOT_EXIST: "评论不存在或不允许回复",
+	ERROR_COMMENT_GUEST_OFF: "本站未开放游客评论，请登录后评论",
+	ERROR_COMMENT_NAME_USED: "该昵称已被注册用户使用，请登录后评论或更换昵称",
+	ERROR_COMMENT_THROTTLED: "评论过于频繁，请稍后再试",
 }
 
 func GetErrMsg(code int) string {
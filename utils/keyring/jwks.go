@@ -0,0 +1,60 @@
+package keyring
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+)
+
+// JWK 公钥的 JSON Web Key 表示（RFC 7517）
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519（RFC 8037）
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS 密钥集合
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 导出仍可用于验签的公钥，供其他服务校验本站签发的令牌
+// HS256 共享密钥不能公开，返回空集合
+func (r *Keyring) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	if r.alg == AlgHS256 {
+		return set
+	}
+	r.mu.RLock()
+	keys := append([]*Key(nil), r.keys...)
+	r.mu.RUnlock()
+
+	for _, key := range keys {
+		if _, ok := r.find(key.Kid); !ok {
+			continue
+		}
+		jwk := JWK{Kid: key.Kid, Use: "sig", Alg: key.Alg}
+		switch pub := key.VerifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
@@ -0,0 +1,323 @@
+package keyring
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/wejectchen/ginblog/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 支持的签名算法
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// kidTimeLayout kid 以创建时间开头，轮换与宽限期据此计算
+const kidTimeLayout = "20060102150405"
+
+// Key 签名密钥
+type Key struct {
+	Kid       string
+	Alg       string
+	CreatedAt time.Time
+	// SignKey 签名用的私钥（HS256 为共享密钥）
+	SignKey interface{}
+	// VerifyKey 验签用的公钥（HS256 为共享密钥）
+	VerifyKey interface{}
+}
+
+// Keyring 管理签名密钥
+// 最新的密钥用于签发令牌；被替换下来的旧密钥在宽限期内仍可验签，过期后删除
+type Keyring struct {
+	mu     sync.RWMutex
+	dir    string
+	alg    string
+	rotate time.Duration
+	grace  time.Duration
+	keys   []*Key // 按创建时间升序
+	loaded time.Time
+}
+
+// Default 当前使用的密钥环，由配置 [jwt] 决定
+var Default *Keyring
+
+func init() {
+	if utils.JwtAlg == AlgHS256 {
+		Default = NewSymmetric([]byte(utils.JwtKey))
+		return
+	}
+	// 宽限期不能短于访问令牌有效期，否则轮换后已签发的令牌会提前失效
+	grace := utils.JwtKeyGrace
+	if grace < utils.AccessTokenExpire {
+		grace = utils.AccessTokenExpire
+	}
+	r, err := New(utils.JwtKeyDir, utils.JwtAlg, utils.JwtRotate, grace)
+	if err != nil {
+		fmt.Println("加载JWT签名密钥失败，请检查参数：", err)
+		os.Exit(1)
+	}
+	Default = r
+	go Default.run(time.Hour)
+}
+
+// NewSymmetric HS256 共享密钥，不轮换，也不对外公开
+func NewSymmetric(secret []byte) *Keyring {
+	return &Keyring{
+		alg: AlgHS256,
+		keys: []*Key{{
+			Alg:       AlgHS256,
+			SignKey:   secret,
+			VerifyKey: secret,
+		}},
+	}
+}
+
+// New 从目录加载非对称密钥，没有可用密钥时自动生成
+func New(dir string, alg string, rotate time.Duration, grace time.Duration) (*Keyring, error) {
+	if alg != AlgRS256 && alg != AlgEdDSA {
+		return nil, fmt.Errorf("不支持的签名算法: %s", alg)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	r := &Keyring{dir: dir, alg: alg, rotate: rotate, grace: grace}
+	if err := r.Maintain(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Signing 当前用于签发令牌的密钥
+func (r *Keyring) Signing() *Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.keys[len(r.keys)-1]
+}
+
+// Lookup 按 kid 查找可用于验签的密钥
+// 找不到时重新读取目录，以便多实例部署时识别其他实例轮换出的新密钥
+func (r *Keyring) Lookup(kid string) (*Key, bool) {
+	if r.alg == AlgHS256 {
+		return r.keys[0], true
+	}
+	if key, ok := r.find(kid); ok {
+		return key, true
+	}
+	r.mu.RLock()
+	stale := time.Since(r.loaded) > time.Minute
+	r.mu.RUnlock()
+	if stale && r.reload() == nil {
+		return r.find(kid)
+	}
+	return nil, false
+}
+
+func (r *Keyring) find(kid string) (*Key, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	now := time.Now()
+	for i, key := range r.keys {
+		if key.Kid != kid {
+			continue
+		}
+		// 旧密钥自被替换起计算宽限期
+		if i < len(r.keys)-1 && now.After(r.keys[i+1].CreatedAt.Add(r.grace)) {
+			return nil, false
+		}
+		return key, true
+	}
+	return nil, false
+}
+
+// Methods 允许的签名算法，解析令牌时只接受这些算法
+func (r *Keyring) Methods() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var methods []string
+	for _, key := range r.keys {
+		if !containsString(methods, key.Alg) {
+			methods = append(methods, key.Alg)
+		}
+	}
+	return methods
+}
+
+// Rotate 立即生成新的签名密钥，旧密钥进入宽限期
+func (r *Keyring) Rotate() error {
+	if r.alg == AlgHS256 {
+		return errors.New("HS256 共享密钥不支持轮换")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.generate()
+}
+
+// Maintain 重新读取密钥目录，到期时轮换，并删除宽限期已过的旧密钥
+func (r *Keyring) Maintain() error {
+	if r.alg == AlgHS256 {
+		return nil
+	}
+	if err := r.reload(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	newest := r.newest()
+	if newest == nil || newest.Alg != r.alg || (r.rotate > 0 && now.Sub(newest.CreatedAt) >= r.rotate) {
+		if err := r.generate(); err != nil {
+			return err
+		}
+	}
+
+	keys := r.keys[:0]
+	for i, key := range r.keys {
+		if i < len(r.keys)-1 && now.After(r.keys[i+1].CreatedAt.Add(r.grace)) {
+			_ = os.Remove(filepath.Join(r.dir, key.Kid+".pem"))
+			continue
+		}
+		keys = append(keys, key)
+	}
+	r.keys = keys
+	return nil
+}
+
+func (r *Keyring) run(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := r.Maintain(); err != nil {
+			fmt.Println("JWT签名密钥轮换失败：", err)
+		}
+	}
+}
+
+func (r *Keyring) newest() *Key {
+	if len(r.keys) == 0 {
+		return nil
+	}
+	return r.keys[len(r.keys)-1]
+}
+
+// reload 读取目录下的全部 PEM 私钥
+func (r *Keyring) reload() error {
+	files, err := filepath.Glob(filepath.Join(r.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	var keys []*Key
+	for _, file := range files {
+		key, err := readKey(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = keys
+	r.loaded = time.Now()
+	return nil
+}
+
+// generate 生成新密钥并写入目录，调用方需持有写锁
+func (r *Keyring) generate() error {
+	var signer crypto.Signer
+	var err error
+	switch r.alg {
+	case AlgRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	kid := now.Format(kidTimeLayout) + "-" + hex.EncodeToString(b)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err = os.WriteFile(filepath.Join(r.dir, kid+".pem"), data, 0600); err != nil {
+		return err
+	}
+
+	r.keys = append(r.keys, &Key{
+		Kid:       kid,
+		Alg:       r.alg,
+		CreatedAt: now,
+		SignKey:   signer,
+		VerifyKey: signer.Public(),
+	})
+	return nil
+}
+
+// readKey 读取 PKCS#8 私钥，文件名即 kid
+// 手动放入的密钥文件名不含时间时，以文件修改时间作为创建时间
+func readKey(file string) (*Key, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("不是PEM格式的密钥")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{Kid: strings.TrimSuffix(filepath.Base(file), ".pem")}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Alg, key.SignKey, key.VerifyKey = AlgRS256, k, k.Public()
+	case ed25519.PrivateKey:
+		key.Alg, key.SignKey, key.VerifyKey = AlgEdDSA, k, k.Public()
+	default:
+		return nil, errors.New("仅支持RSA与Ed25519密钥")
+	}
+
+	if len(key.Kid) >= len(kidTimeLayout) {
+		key.CreatedAt, err = time.Parse(kidTimeLayout, key.Kid[:len(kidTimeLayout)])
+	}
+	if key.CreatedAt.IsZero() || err != nil {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		key.CreatedAt = info.ModTime()
+	}
+	return key, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
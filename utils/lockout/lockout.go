@@ -0,0 +1,79 @@
+package lockout
+
+import (
+	"github.com/wejectchen/ginblog/utils"
+	"github.com/wejectchen/ginblog/utils/store"
+	"time"
+)
+
+// 登录防爆破：按账号和IP分别统计失败次数
+// 超过免费次数后按指数退避暂时拒绝登录，达到上限后锁定
+
+func failKey(kind string, name string) string {
+	return "login:fail:" + kind + ":" + name
+}
+
+func lockKey(kind string, name string) string {
+	return "login:lock:" + kind + ":" + name
+}
+
+// Check 登录前检查账号或IP是否处于等待/锁定中，返回需要等待的时间
+func Check(ip string, username string) (time.Duration, bool) {
+	var wait time.Duration
+	for _, key := range []string{lockKey("ip", ip), lockKey("user", username)} {
+		ttl, _ := store.Default.TTL(key)
+		if ttl > wait {
+			wait = ttl
+		}
+	}
+	return wait, wait > 0
+}
+
+// Fail 记录一次登录失败
+func Fail(ip string, username string) {
+	fail("ip", ip, utils.LoginIpFreeTries, utils.LoginIpMaxTries)
+	if username != "" {
+		fail("user", username, utils.LoginFreeAttempts, utils.LoginMaxAttempts)
+	}
+}
+
+func fail(kind string, name string, free int, max int) {
+	n, err := store.Default.Incr(failKey(kind, name), utils.LoginFailWindow)
+	if err != nil || n <= int64(free) {
+		return
+	}
+	if n >= int64(max) {
+		_ = store.Default.Set(lockKey(kind, name), "1", utils.LoginLockDuration)
+		return
+	}
+	_ = store.Default.Set(lockKey(kind, name), "1", backoff(n-int64(free)))
+}
+
+// backoff 第 n 次超额失败需要等待的时间：base * 2^(n-1)，不超过上限
+func backoff(n int64) time.Duration {
+	wait := utils.LoginBackoffBase
+	for i := int64(1); i < n; i++ {
+		wait *= 2
+		if wait >= utils.LoginBackoffMax {
+			return utils.LoginBackoffMax
+		}
+	}
+	return wait
+}
+
+// Success 登录成功后清除账号的失败记录
+func Success(username string) {
+	_ = store.Default.Del(failKey("user", username))
+}
+
+// Unlock 管理员解锁账号
+func Unlock(username string) {
+	_ = store.Default.Del(failKey("user", username))
+	_ = store.Default.Del(lockKey("user", username))
+}
+
+// UnlockIp 管理员解锁IP
+func UnlockIp(ip string) {
+	_ = store.Default.Del(failKey("ip", ip))
+	_ = store.Default.Del(lockKey("ip", ip))
+}
@@ -0,0 +1,97 @@
+package mailer
+
+import (
+	"fmt"
+	"github.com/wejectchen/ginblog/utils"
+	"log"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Mailer 邮件发送接口，可按需替换为其他实现
+type Mailer interface {
+	Send(to string, subject string, body string) error
+}
+
+// Default 当前使用的发送器，由配置 [mail] Driver 决定
+var Default Mailer
+
+func init() {
+	switch utils.MailDriver {
+	case "smtp":
+		Default = &SMTPMailer{
+			Host:     utils.MailHost,
+			Port:     utils.MailPort,
+			Username: utils.MailUsername,
+			Password: utils.MailPassword,
+			From:     utils.MailFrom,
+		}
+	default:
+		Default = &FileMailer{Dir: utils.MailFileDir, From: utils.MailFrom}
+	}
+}
+
+// Send 异步发送邮件，发送失败只记录日志
+func Send(to string, subject string, body string) {
+	go func() {
+		if err := Default.Send(to, subject, body); err != nil {
+			log.Println("邮件发送失败:", to, err)
+		}
+	}()
+}
+
+func buildMessage(from string, to string, subject string, body string) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + to + "\r\n")
+	// 中文主题需要按 RFC 2047 编码
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(body)
+	return []byte(b.String())
+}
+
+// SMTPMailer 通过 SMTP 服务器发送，未配置用户名时不做认证（便于对接本地测试服务器）
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Send 发件人可以带显示名称（如 GinBlog <noreply@example.com>），信封发件人只使用其中的邮箱地址
+func (m *SMTPMailer) Send(to string, subject string, body string) error {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("发件人地址 %q 不正确: %w", m.From, err)
+	}
+	addr := fmt.Sprintf("%s:%d", m.Host, m.Port)
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	return smtp.SendMail(addr, auth, from.Address, []string{to}, buildMessage(from.String(), to, subject, body))
+}
+
+// FileMailer 把邮件写入目录下的 .eml 文件，开发和测试时使用
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m *FileMailer) Send(to string, subject string, body string) error {
+	if err := os.MkdirAll(m.Dir, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102150405.000000"), strings.ReplaceAll(to, "@", "_at_"))
+	return os.WriteFile(filepath.Join(m.Dir, name), buildMessage(m.From, to, subject, body), 0644)
+}
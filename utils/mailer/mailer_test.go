@@ -0,0 +1,87 @@
+package mailer
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeSMTP 只实现发送一封邮件所需命令的 SMTP 服务器，返回收到的命令与邮件内容
+func fakeSMTP(t *testing.T) (port int, done <-chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan []string, 1)
+	go func() {
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			ch <- nil
+			return
+		}
+		defer conn.Close()
+		var lines []string
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost")
+		data := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch {
+			case data && line == ".":
+				data = false
+				reply("250 OK")
+			case data:
+			case strings.HasPrefix(line, "EHLO"), strings.HasPrefix(line, "HELO"):
+				reply("250 localhost")
+			case line == "DATA":
+				data = true
+				reply("354 Go ahead")
+			case line == "QUIT":
+				reply("221 Bye")
+				ch <- lines
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+		ch <- lines
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, ch
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	port, done := fakeSMTP(t)
+	m := &SMTPMailer{Host: "127.0.0.1", Port: port, From: "GinBlog <noreply@localhost>"}
+	if err := m.Send("user@example.com", "GinBlog 找回密码", "你好"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Join(<-done, "\n")
+
+	for _, want := range []string{
+		"MAIL FROM:<noreply@localhost>",
+		"RCPT TO:<user@example.com>",
+		`From: "GinBlog" <noreply@localhost>`,
+		"Subject: =?utf-8?q?GinBlog_=E6=89=BE=E5=9B=9E=E5=AF=86=E7=A0=81?=",
+	} {
+		if !strings.Contains(lines, want) {
+			t.Errorf("缺少 %q，收到:\n%s", want, lines)
+		}
+	}
+}
+
+func TestSMTPMailerBadFrom(t *testing.T) {
+	m := &SMTPMailer{Host: "127.0.0.1", Port: 1, From: "GinBlog <noreply"}
+	if err := m.Send("user@example.com", "subject", "body"); err == nil || !strings.Contains(err.Error(), strconv.Quote(m.From)) {
+		t.Errorf("发件人格式错误时应返回说明，得到 %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package markdown
+
+import (
+	"bytes"
+	"github.com/wejectchen/ginblog/utils/slug"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"strconv"
+)
+
+// md CommonMark 加 GFM（表格、删除线、任务列表、自动链接）与脚注
+// 代码块输出为 <pre><code class="language-go">，由前端的 highlight.js 等按类名着色
+var md = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, extension.Footnote),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	// 保留正文中的原始 HTML
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+// Render 将 Markdown 渲染为 HTML
+func Render(source string) (string, error) {
+	var buf bytes.Buffer
+	ctx := parser.NewContext(parser.WithIDs(&headingIDs{seen: map[string]bool{}}))
+	if err := md.Convert([]byte(source), &buf, parser.WithContext(ctx)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// headingIDs 标题锚点与文章 slug 规则一致，中文标题转换为拼音，重复时追加序号
+type headingIDs struct {
+	seen map[string]bool
+}
+
+func (h *headingIDs) Generate(value []byte, _ ast.NodeKind) []byte {
+	base := slug.Make(string(value))
+	if base == "" {
+		base = "heading"
+	}
+	id := base
+	for i := 1; h.seen[id]; i++ {
+		id = base + "-" + strconv.Itoa(i)
+	}
+	h.seen[id] = true
+	return []byte(id)
+}
+
+func (h *headingIDs) Put(value []byte) {
+	h.seen[string(value)] = true
+}
@@ -0,0 +1,57 @@
+package oauth
+
+import (
+	"context"
+	"github.com/wejectchen/ginblog/utils"
+	"golang.org/x/oauth2"
+	"strconv"
+)
+
+type gitee struct {
+	config *oauth2.Config
+}
+
+func newGitee(conf utils.OAuthProvider) *gitee {
+	scopes := conf.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"user_info", "emails"}
+	}
+	return &gitee{config: &oauth2.Config{
+		ClientID:     conf.ClientId,
+		ClientSecret: conf.ClientSecret,
+		RedirectURL:  conf.RedirectUrl,
+		Scopes:       scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   "https://gitee.com/oauth/authorize",
+			TokenURL:  "https://gitee.com/oauth/token",
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}}
+}
+
+func (g *gitee) Config(_ context.Context) (*oauth2.Config, error) {
+	return g.config, nil
+}
+
+func (g *gitee) UserInfo(ctx context.Context, token *oauth2.Token, _ string) (*Identity, error) {
+	var user struct {
+		Id    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	// 码云 v5 接口通过 access_token 参数鉴权
+	url := "https://gitee.com/api/v5/user?access_token=" + token.AccessToken
+	if err := getJSON(ctx, url, nil, "", &user); err != nil {
+		return nil, err
+	}
+	if user.Id == 0 {
+		return nil, errNoSubject
+	}
+	return &Identity{
+		Subject:  strconv.FormatInt(user.Id, 10),
+		Username: user.Login,
+		Name:     user.Name,
+		Email:    user.Email,
+	}, nil
+}
@@ -0,0 +1,54 @@
+package oauth
+
+import (
+	"context"
+	"github.com/wejectchen/ginblog/utils"
+	"golang.org/x/oauth2"
+	"strconv"
+)
+
+type github struct {
+	config *oauth2.Config
+}
+
+func newGithub(conf utils.OAuthProvider) *github {
+	scopes := conf.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"read:user", "user:email"}
+	}
+	return &github{config: &oauth2.Config{
+		ClientID:     conf.ClientId,
+		ClientSecret: conf.ClientSecret,
+		RedirectURL:  conf.RedirectUrl,
+		Scopes:       scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  "https://github.com/login/oauth/authorize",
+			TokenURL: "https://github.com/login/oauth/access_token",
+		},
+	}}
+}
+
+func (g *github) Config(_ context.Context) (*oauth2.Config, error) {
+	return g.config, nil
+}
+
+func (g *github) UserInfo(ctx context.Context, token *oauth2.Token, _ string) (*Identity, error) {
+	var user struct {
+		Id    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	if err := getJSON(ctx, "https://api.github.com/user", token, "Bearer", &user); err != nil {
+		return nil, err
+	}
+	if user.Id == 0 {
+		return nil, errNoSubject
+	}
+	return &Identity{
+		Subject:  strconv.FormatInt(user.Id, 10),
+		Username: user.Login,
+		Name:     user.Name,
+		Email:    user.Email,
+	}, nil
+}
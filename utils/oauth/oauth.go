@@ -0,0 +1,96 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wejectchen/ginblog/utils"
+	"golang.org/x/oauth2"
+	"net/http"
+	"time"
+)
+
+// Identity 第三方平台返回的用户身份
+type Identity struct {
+	Subject  string
+	Username string
+	Name     string
+	Email    string
+}
+
+// Provider 第三方登录提供方
+type Provider interface {
+	// Config 返回授权码流程的 OAuth2 配置
+	Config(ctx context.Context) (*oauth2.Config, error)
+	// UserInfo 使用访问令牌获取用户身份，nonce 为授权请求时生成的随机值，用于校验 OIDC 的 ID 令牌
+	UserInfo(ctx context.Context, token *oauth2.Token, nonce string) (*Identity, error)
+}
+
+var providers = map[string]Provider{}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+func init() {
+	for name, conf := range utils.OAuthProviders {
+		Register(name, conf)
+	}
+}
+
+// Register 按名称启用提供方，不支持的名称返回 false
+func Register(name string, conf utils.OAuthProvider) bool {
+	switch name {
+	case "github":
+		providers[name] = newGithub(conf)
+	case "gitee":
+		providers[name] = newGitee(conf)
+	case "oidc":
+		providers[name] = newOidc(conf)
+	default:
+		return false
+	}
+	return true
+}
+
+// Get 按名称获取已启用的提供方
+func Get(name string) (Provider, bool) {
+	p, ok := providers[name]
+	return p, ok
+}
+
+// Names 已启用的提供方名称
+func Names() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Context 第三方请求统一使用带超时的 http 客户端
+func Context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+}
+
+// getJSON 携带访问令牌请求接口并解析 JSON
+func getJSON(ctx context.Context, url string, token *oauth2.Token, authScheme string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if token != nil {
+		req.Header.Set("Authorization", authScheme+" "+token.AccessToken)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("请求 %s 失败: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+var errNoSubject = errors.New("第三方平台未返回用户标识")
@@ -0,0 +1,197 @@
+package oauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/wejectchen/ginblog/utils"
+	"golang.org/x/oauth2"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oidc 通用 OpenID Connect 提供方，端点通过服务发现获取
+// 首次使用时才请求发现文档，提供方暂时不可用不会影响服务启动
+type oidc struct {
+	conf utils.OAuthProvider
+
+	mu       sync.Mutex
+	config   *oauth2.Config
+	issuer   string
+	userinfo string
+	jwksUri  string
+	// keys 按 kid 缓存的验签公钥，遇到未知 kid 时重新获取，以支持提供方轮换密钥
+	keys map[string]interface{}
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+// idTokenClaims ID 令牌中需要校验的声明
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce string `json:"nonce"`
+	Azp   string `json:"azp"`
+}
+
+// ID 令牌允许的签名算法，不接受 none 与使用客户端密钥的 HS 系列
+var idTokenAlgs = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+func newOidc(conf utils.OAuthProvider) *oidc {
+	if len(conf.Scopes) == 0 {
+		conf.Scopes = []string{"openid", "profile", "email"}
+	}
+	return &oidc{conf: conf}
+}
+
+func (o *oidc) Config(ctx context.Context) (*oauth2.Config, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.config != nil {
+		return o.config, nil
+	}
+
+	issuer := strings.TrimRight(o.conf.Issuer, "/")
+	var doc discovery
+	if err := getJSON(ctx, issuer+"/.well-known/openid-configuration", nil, "", &doc); err != nil {
+		return nil, err
+	}
+	if strings.TrimRight(doc.Issuer, "/") != issuer {
+		return nil, errors.New("OIDC 发现文档中的 issuer 与配置不一致")
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.UserinfoEndpoint == "" || doc.JwksUri == "" {
+		return nil, errors.New("OIDC 发现文档缺少必要的端点")
+	}
+
+	o.issuer = doc.Issuer
+	o.userinfo = doc.UserinfoEndpoint
+	o.jwksUri = doc.JwksUri
+	o.config = &oauth2.Config{
+		ClientID:     o.conf.ClientId,
+		ClientSecret: o.conf.ClientSecret,
+		RedirectURL:  o.conf.RedirectUrl,
+		Scopes:       o.conf.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  doc.AuthorizationEndpoint,
+			TokenURL: doc.TokenEndpoint,
+		},
+	}
+	return o.config, nil
+}
+
+// UserInfo 先校验令牌响应中的 ID 令牌（签名、iss、aud、exp 与 nonce），再从 userinfo 端点补充资料
+// userinfo 返回的 sub 必须与 ID 令牌一致，防止令牌被替换
+func (o *oidc) UserInfo(ctx context.Context, token *oauth2.Token, nonce string) (*Identity, error) {
+	if _, err := o.Config(ctx); err != nil {
+		return nil, err
+	}
+	raw, _ := token.Extra("id_token").(string)
+	if raw == "" {
+		return nil, errors.New("OIDC 令牌响应中缺少 id_token")
+	}
+	subject, err := o.verifyIdToken(ctx, raw, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims struct {
+		Sub               string `json:"sub"`
+		PreferredUsername string `json:"preferred_username"`
+		Name              string `json:"name"`
+		Email             string `json:"email"`
+		EmailVerified     bool   `json:"email_verified"`
+	}
+	if err := getJSON(ctx, o.userinfo, token, "Bearer", &claims); err != nil {
+		return nil, err
+	}
+	if claims.Sub != subject {
+		return nil, errors.New("userinfo 返回的 sub 与 ID 令牌不一致")
+	}
+	identity := &Identity{
+		Subject:  claims.Sub,
+		Username: claims.PreferredUsername,
+		Name:     claims.Name,
+	}
+	// 只信任提供方已验证过的邮箱
+	if claims.EmailVerified {
+		identity.Email = claims.Email
+	}
+	return identity, nil
+}
+
+// verifyIdToken 校验 ID 令牌并返回其中的 sub
+func (o *oidc) verifyIdToken(ctx context.Context, raw string, nonce string) (string, error) {
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return o.key(ctx, kid)
+	},
+		jwt.WithValidMethods(idTokenAlgs),
+		jwt.WithIssuer(o.issuer),
+		jwt.WithAudience(o.conf.ClientId),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return "", err
+	}
+	// 多个受众时 azp 必须是本站
+	if len(claims.Audience) > 1 && claims.Azp != o.conf.ClientId {
+		return "", errors.New("ID 令牌的 azp 与 ClientId 不一致")
+	}
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return "", errors.New("ID 令牌的 nonce 与授权请求不一致")
+	}
+	if claims.Subject == "" {
+		return "", errNoSubject
+	}
+	return claims.Subject, nil
+}
+
+// key 按 kid 查找验签公钥，缓存中没有时重新获取 JWKS
+// 令牌没有 kid 时只有 JWKS 中恰好一个密钥才能使用
+func (o *oidc) key(ctx context.Context, kid string) (interface{}, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if key, ok := o.findKey(kid); ok {
+		return key, nil
+	}
+
+	var set jwks
+	if err := getJSON(ctx, o.jwksUri, nil, "", &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	o.keys = keys
+	if key, ok := o.findKey(kid); ok {
+		return key, nil
+	}
+	return nil, errors.New("找不到 ID 令牌的验签公钥")
+}
+
+func (o *oidc) findKey(kid string) (interface{}, bool) {
+	if kid == "" && len(o.keys) == 1 {
+		for _, key := range o.keys {
+			return key, true
+		}
+	}
+	key, ok := o.keys[kid]
+	return key, ok
+}
@@ -0,0 +1,89 @@
+package oauth
+
+import (
+	"context"
+	"github.com/wejectchen/ginblog/utils"
+	"github.com/wejectchen/ginblog/utils/oauth/oauthtest"
+	"golang.org/x/oauth2"
+	"testing"
+)
+
+// login 走一遍授权码流程，verifier 为空时使用授权请求中的 PKCE 验证码
+func login(t *testing.T, server *oauthtest.Server, verifier string, nonce string) (*Identity, error) {
+	t.Helper()
+	ctx := Context(context.Background())
+	provider := newOidc(utils.OAuthProvider{
+		ClientId:     oauthtest.ClientId,
+		ClientSecret: oauthtest.ClientSecret,
+		RedirectUrl:  "http://localhost:3000/api/v1/oauth/oidc/callback",
+		Issuer:       server.Issuer(),
+	})
+	config, err := provider.Config(ctx)
+	if err != nil {
+		t.Fatalf("服务发现失败: %v", err)
+	}
+
+	challenge := oauth2.GenerateVerifier()
+	authUrl := config.AuthCodeURL("state-1", oauth2.S256ChallengeOption(challenge), oauth2.SetAuthURLParam("nonce", "nonce-1"))
+	code, state, err := server.Authorize(authUrl)
+	if err != nil {
+		t.Fatalf("授权失败: %v", err)
+	}
+	if state != "state-1" {
+		t.Fatalf("state = %q，应原样返回", state)
+	}
+	if verifier == "" {
+		verifier = challenge
+	}
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, err
+	}
+	return provider.UserInfo(ctx, token, nonce)
+}
+
+func TestOidcLogin(t *testing.T) {
+	server := oauthtest.NewServer()
+	defer server.Close()
+
+	identity, err := login(t, server, "", "nonce-1")
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if identity.Subject != server.User.Subject || identity.Username != server.User.Username || identity.Email != server.User.Email {
+		t.Errorf("identity = %+v，与提供方用户 %+v 不一致", identity, server.User)
+	}
+}
+
+func TestOidcPKCE(t *testing.T) {
+	server := oauthtest.NewServer()
+	defer server.Close()
+
+	if _, err := login(t, server, oauth2.GenerateVerifier(), "nonce-1"); err == nil {
+		t.Error("code_verifier 与挑战不一致时不应换取到令牌")
+	}
+}
+
+func TestOidcIdToken(t *testing.T) {
+	cases := []struct {
+		name   string
+		tamper func(s *oauthtest.Server)
+		nonce  string
+	}{
+		{"nonce 不一致", func(s *oauthtest.Server) { s.Nonce = "replayed" }, "nonce-1"},
+		{"回调缺少 nonce", func(s *oauthtest.Server) {}, ""},
+		{"aud 不是本站", func(s *oauthtest.Server) { s.Audience = "other-client" }, "nonce-1"},
+		{"userinfo sub 不一致", func(s *oauthtest.Server) { s.UserinfoSubject = "20002" }, "nonce-1"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := oauthtest.NewServer()
+			defer server.Close()
+			tc.tamper(server)
+
+			if identity, err := login(t, server, "", tc.nonce); err == nil {
+				t.Errorf("应拒绝登录，得到 %+v", identity)
+			}
+		})
+	}
+}
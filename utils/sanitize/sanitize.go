@@ -0,0 +1,89 @@
+package sanitize
+
+import (
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/wejectchen/ginblog/utils"
+	"regexp"
+	"strings"
+)
+
+// 策略在启动时按 [sanitize] 配置生成，bluemonday 的策略生成后可并发使用
+var (
+	commentPolicy = newCommentPolicy()
+	articlePolicy = newArticlePolicy(false)
+	trustedPolicy = newArticlePolicy(true)
+	textPolicy    = bluemonday.StrictPolicy()
+)
+
+// Comment 按评论白名单过滤，评论来自普通读者，只保留简单的排版标签
+func Comment(html string) string {
+	return commentPolicy.Sanitize(html)
+}
+
+// Article 按文章白名单过滤，trusted 为 true 时额外允许音视频与指定域名的 iframe
+func Article(html string, trusted bool) string {
+	if trusted {
+		return trustedPolicy.Sanitize(html)
+	}
+	return articlePolicy.Sanitize(html)
+}
+
+// Text 去除全部标签，只保留转义后的文本
+func Text(html string) string {
+	return textPolicy.Sanitize(html)
+}
+
+// newCommentPolicy 链接只允许 http、https、mailto，统一加上 nofollow 并在新窗口打开
+func newCommentPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	for _, tag := range utils.SanitizeCommentTags {
+		switch tag {
+		case "a":
+			p.AllowAttrs("href").OnElements("a")
+			p.AllowStandardURLs()
+			p.AddTargetBlankToFullyQualifiedLinks(true)
+		case "code":
+			p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[a-zA-Z0-9+#-]+$`)).OnElements("code")
+		}
+		p.AllowElements(tag)
+	}
+	return p
+}
+
+// newArticlePolicy 在 bluemonday 的 UGC 策略（标题、列表、表格、图片、代码等）基础上
+// 允许编辑器与 Markdown 生成的类名、任务列表复选框以及配置的行内样式
+func newArticlePolicy(trusted bool) *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^[a-zA-Z0-9_\- ]+$`)).Globally()
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	p.AllowAttrs("target").Matching(regexp.MustCompile(`^_blank$`)).OnElements("a")
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	p.RequireNoFollowOnLinks(false)
+	if len(utils.SanitizeArticleStyles) > 0 {
+		p.AllowStyles(utils.SanitizeArticleStyles...).Globally()
+	}
+	if len(utils.SanitizeArticleTags) > 0 {
+		p.AllowElements(utils.SanitizeArticleTags...)
+	}
+	if !trusted {
+		return p
+	}
+
+	p.AllowElements("video", "audio", "source")
+	p.AllowAttrs("src", "poster").OnElements("video", "audio", "source")
+	p.AllowAttrs("type").OnElements("source")
+	p.AllowAttrs("controls", "loop", "muted", "preload", "width", "height").OnElements("video", "audio")
+	if len(utils.SanitizeIframeHosts) > 0 {
+		hosts := make([]string, len(utils.SanitizeIframeHosts))
+		for i, host := range utils.SanitizeIframeHosts {
+			hosts[i] = regexp.QuoteMeta(host)
+		}
+		// 嵌入代码常用 //player.bilibili.com/... 这样省略协议的写法
+		src := regexp.MustCompile(`^(https?:)?//(` + strings.Join(hosts, "|") + `)(/|$)`)
+		p.AllowElements("iframe")
+		p.AllowAttrs("src").Matching(src).OnElements("iframe")
+		p.AllowAttrs("width", "height", "frameborder", "allow", "allowfullscreen", "scrolling").OnElements("iframe")
+	}
+	return p
+}
@@ -0,0 +1,100 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+)
+
+// policies 每个用例都在评论、普通文章与可信文章三种策略下检查
+var policies = []struct {
+	name     string
+	sanitize func(string) string
+}{
+	{"comment", Comment},
+	{"article", func(html string) string { return Article(html, false) }},
+	{"trusted", func(html string) string { return Article(html, true) }},
+}
+
+func TestXSSVectors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		// deny 过滤结果（转为小写后）中不能出现的内容
+		deny []string
+	}{
+		{"script", `<script>alert(1)</script>`, []string{"<script", "alert(1)"}},
+		{"script 大小写", `<ScRiPt src="//evil.example.com/x.js"></ScRiPt>`, []string{"<script", "evil.example.com"}},
+		{"img onerror", `<img src="x" onerror="alert(1)">`, []string{"onerror"}},
+		{"img 无引号 onerror", `<img src=x onerror=alert(1)//>`, []string{"onerror"}},
+		{"事件属性", `<b onclick="alert(1)">x</b><p onmouseover="alert(1)">y</p>`, []string{"onclick", "onmouseover"}},
+		{"javascript 链接", `<a href="javascript:alert(1)">x</a>`, []string{"javascript:"}},
+		{"javascript 链接大小写", `<a href="JaVaScRiPt:alert(1)">x</a>`, []string{"javascript:"}},
+		{"javascript 链接实体编码", `<a href="&#106;avascript:alert(1)">x</a>`, []string{"avascript:", "&#106;"}},
+		{"javascript 链接含空白", "<a href=\"java\tscript:alert(1)\">x</a>", []string{"script:"}},
+		{"data 链接", `<a href="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">x</a>`, []string{"data:"}},
+		{"data 图片", `<img src="data:image/svg+xml;base64,PHN2ZyBvbmxvYWQ9YWxlcnQoMSk+">`, []string{"data:"}},
+		{"svg onload", `<svg onload="alert(1)"><circle r="1"/></svg>`, []string{"<svg", "onload"}},
+		{"svg script", `<svg><script>alert(1)</script></svg>`, []string{"<svg", "<script"}},
+		{"未允许的 iframe", `<iframe src="https://evil.example.com/x"></iframe>`, []string{"<iframe", "evil.example.com"}},
+		{"域名后缀伪造的 iframe", `<iframe src="https://player.bilibili.com.evil.example.com/x"></iframe>`, []string{"<iframe", "evil.example.com"}},
+		{"javascript iframe", `<iframe src="javascript:alert(1)"></iframe>`, []string{"<iframe", "javascript:"}},
+		{"srcdoc iframe", `<iframe srcdoc="<script>alert(1)</script>"></iframe>`, []string{"srcdoc", "<script"}},
+		{"style expression", `<p style="width: expression(alert(1))">x</p>`, []string{"expression"}},
+		{"style javascript url", `<p style="background-color: url(javascript:alert(1))">x</p>`, []string{"javascript", "url("}},
+		{"style 标签", `<style>body{background:url("javascript:alert(1)")}</style>`, []string{"<style", "javascript"}},
+		{"object/embed", `<object data="evil.swf"></object><embed src="evil.swf">`, []string{"<object", "<embed", "evil.swf"}},
+		{"form", `<form action="javascript:alert(1)"><input type="submit"></form>`, []string{"<form", "javascript:", "submit"}},
+		{"meta refresh", `<meta http-equiv="refresh" content="0;url=javascript:alert(1)">`, []string{"<meta", "javascript"}},
+		{"base href", `<base href="javascript:alert(1)//">`, []string{"<base", "javascript"}},
+		{"mutation", `<math><mtext><table><mglyph><style><img src=x onerror=alert(1)>`, []string{"onerror"}},
+	}
+	for _, tt := range tests {
+		for _, p := range policies {
+			t.Run(tt.name+"/"+p.name, func(t *testing.T) {
+				out := strings.ToLower(p.sanitize(tt.input))
+				for _, deny := range tt.deny {
+					if strings.Contains(out, deny) {
+						t.Errorf("%q -> %q, 不应包含 %q", tt.input, out, deny)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestSafeMarkupKept(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		policy string
+		want   string
+	}{
+		{"评论加粗", `<b>x</b>`, "comment", `<b>x</b>`},
+		{"评论链接", `<a href="https://example.com">x</a>`, "comment", `href="https://example.com"`},
+		{"评论链接 nofollow", `<a href="https://example.com">x</a>`, "comment", `rel="nofollow`},
+		{"文章图片", `<img src="https://example.com/a.png" alt="a">`, "article", `src="https://example.com/a.png"`},
+		{"文章行内样式", `<p style="text-align: center">x</p>`, "article", `text-align: center`},
+		{"可信文章 iframe", `<iframe src="//player.bilibili.com/player.html?bvid=1"></iframe>`, "trusted", `<iframe src="//player.bilibili.com/player.html?bvid=1"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, p := range policies {
+				if p.name != tt.policy {
+					continue
+				}
+				if out := p.sanitize(tt.input); !strings.Contains(out, tt.want) {
+					t.Errorf("%q -> %q, 应包含 %q", tt.input, out, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUntrustedArticleDropsIframe(t *testing.T) {
+	input := `<iframe src="//player.bilibili.com/player.html?bvid=1"></iframe><video src="https://example.com/a.mp4"></video>`
+	for _, p := range policies[:2] {
+		if out := strings.ToLower(p.sanitize(input)); strings.Contains(out, "<iframe") || strings.Contains(out, "<video") {
+			t.Errorf("%s: %q 不应保留 iframe 或 video", p.name, out)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 标题与描述中的词比正文更能代表文章主题，计算词频时加权
+const (
+	titleWeight = 3
+	descWeight  = 2
+)
+
+// BM25 参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+type memoryDoc struct {
+	cid         int
+	tagIds      []uint
+	publishedAt time.Time
+	// length 加权后的词数
+	length float64
+	terms  []string
+}
+
+// MemoryEngine 进程内的倒排索引，按 BM25 计算相关度
+// 适合单实例部署，启动时需要从数据库重建
+type MemoryEngine struct {
+	mu       sync.RWMutex
+	docs     map[uint]*memoryDoc
+	postings map[string]map[uint]float64
+	totalLen float64
+}
+
+func NewMemoryEngine() *MemoryEngine {
+	return &MemoryEngine{
+		docs:     make(map[uint]*memoryDoc),
+		postings: make(map[string]map[uint]float64),
+	}
+}
+
+func (e *MemoryEngine) Index(doc Document) error {
+	freq := make(map[string]float64)
+	length := 0.0
+	for _, field := range []struct {
+		text   string
+		weight float64
+	}{{doc.Title, titleWeight}, {doc.Desc, descWeight}, {doc.Content, 1}} {
+		for _, token := range Tokenize(field.text) {
+			freq[token] += field.weight
+			length += field.weight
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.remove(doc.ID)
+	d := &memoryDoc{
+		cid:         doc.Cid,
+		tagIds:      doc.TagIds,
+		publishedAt: doc.PublishedAt,
+		length:      length,
+		terms:       make([]string, 0, len(freq)),
+	}
+	for term, f := range freq {
+		list, ok := e.postings[term]
+		if !ok {
+			list = make(map[uint]float64)
+			e.postings[term] = list
+		}
+		list[doc.ID] = f
+		d.terms = append(d.terms, term)
+	}
+	e.docs[doc.ID] = d
+	e.totalLen += length
+	return nil
+}
+
+func (e *MemoryEngine) Delete(id uint) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.remove(id)
+	return nil
+}
+
+// remove 调用方需持有写锁
+func (e *MemoryEngine) remove(id uint) {
+	d, ok := e.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range d.terms {
+		delete(e.postings[term], id)
+		if len(e.postings[term]) == 0 {
+			delete(e.postings, term)
+		}
+	}
+	e.totalLen -= d.length
+	delete(e.docs, id)
+}
+
+func (e *MemoryEngine) Search(q Query) ([]Hit, int64, error) {
+	terms := QueryTerms(q.Text)
+	if len(terms) == 0 {
+		return nil, 0, nil
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	lists := make([]map[uint]float64, len(terms))
+	for i, term := range terms {
+		lists[i] = e.postings[term]
+		if len(lists[i]) == 0 {
+			return nil, 0, nil
+		}
+	}
+	// 从最短的倒排表出发求交集
+	sort.Slice(lists, func(i, j int) bool { return len(lists[i]) < len(lists[j]) })
+
+	n := float64(len(e.docs))
+	avgLen := e.totalLen / n
+	var hits []Hit
+	for id := range lists[0] {
+		d := e.docs[id]
+		if !d.match(q) {
+			continue
+		}
+		score := 0.0
+		for _, list := range lists {
+			f, ok := list[id]
+			if !ok {
+				score = -1
+				break
+			}
+			df := float64(len(list))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			score += idf * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*d.length/avgLen))
+		}
+		if score >= 0 {
+			hits = append(hits, Hit{ID: id, Score: score})
+		}
+	}
+	// 相关度相同时新发布的文章在前
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return e.docs[hits[i].ID].publishedAt.After(e.docs[hits[j].ID].publishedAt)
+	})
+
+	total := int64(len(hits))
+	if q.Offset < 0 || q.Offset >= len(hits) {
+		return nil, total, nil
+	}
+	hits = hits[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(hits) {
+		hits = hits[:q.Limit]
+	}
+	return hits, total, nil
+}
+
+func (d *memoryDoc) match(q Query) bool {
+	if q.Cid > 0 && d.cid != q.Cid {
+		return false
+	}
+	if !q.From.IsZero() && d.publishedAt.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !d.publishedAt.Before(q.To) {
+		return false
+	}
+	if q.TagId > 0 {
+		for _, id := range d.tagIds {
+			if id == q.TagId {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package search
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryEngineSearchOffset(t *testing.T) {
+	e := NewMemoryEngine()
+	for i := uint(1); i <= 3; i++ {
+		_ = e.Index(Document{ID: i, Title: "Gin 搭建博客", PublishedAt: time.Unix(int64(i), 0)})
+	}
+
+	tests := []struct {
+		name   string
+		offset int
+		want   int
+	}{
+		{"首页", 0, 2},
+		{"末页", 2, 1},
+		{"超出范围", 3, 0},
+		{"负数", -20, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, total, err := e.Search(Query{Text: "博客", Offset: tt.offset, Limit: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != 3 || len(hits) != tt.want {
+				t.Fatalf("offset %d: got %d hits of %d, want %d of 3", tt.offset, len(hits), total, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package search
+
+import "time"
+
+// Document 参与索引的文章内容，Content 为去除标签后的纯文本
+type Document struct {
+	ID          uint
+	Title       string
+	Desc        string
+	Content     string
+	Cid         int
+	TagIds      []uint
+	PublishedAt time.Time
+}
+
+// Query 搜索条件，Cid、TagId 为 0 以及 From、To 为零值时不筛选
+type Query struct {
+	Text   string
+	Cid    int
+	TagId  uint
+	From   time.Time
+	To     time.Time
+	Offset int
+	Limit  int
+}
+
+// Hit 命中的文章，按相关度从高到低排列
+type Hit struct {
+	ID    uint
+	Score float64
+}
+
+// Engine 搜索后端，只负责返回命中的文章ID，文章内容与摘要由调用方查询数据库得到
+// 默认使用进程内的索引，也可以切换为 MySQL 全文索引
+type Engine interface {
+	// Index 新增或更新文档
+	Index(doc Document) error
+	// Delete 从索引中移除文档，文档不存在时不报错
+	Delete(id uint) error
+	// Search 返回当前页的命中结果与命中总数
+	Search(q Query) ([]Hit, int64, error)
+}
@@ -0,0 +1,321 @@
+package search
+
+import (
+	"github.com/wejectchen/ginblog/utils/slug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Suggestion 搜索建议，Type 为 article 或 tag
+type Suggestion struct {
+	Type string `json:"type"`
+	Id   uint   `json:"id"`
+	Text string `json:"text"`
+	Slug string `json:"slug"`
+}
+
+// 建议的匹配键只取前若干个字符，从词中间开始的匹配只取前若干个词
+const (
+	suggestKeyLen    = 24
+	suggestMaxStarts = 8
+	// suggestScan 每次查询最多收集的候选数量
+	suggestScan = 200
+	// suggestMinFuzzy 按音节匹配的最短查询长度，太短的查询几乎能匹配所有标题
+	suggestMinFuzzy = 2
+)
+
+// 匹配方式，数值越小排序越靠前
+const (
+	matchStart    = iota // 从标题开头匹配
+	matchMiddle          // 从词中间开始匹配
+	matchSyllable        // 按音节前缀匹配，可跳过中间的字
+)
+
+type trieNode struct {
+	children map[rune]*trieNode
+	// items 以该节点结尾的匹配键，值表示是否从标题开头匹配
+	items map[string]bool
+}
+
+type suggestItem struct {
+	Suggestion
+	weight int
+	keys   []string
+	// syllables 标题按字拆分的拼音与单词，用于前缀树之外的音节匹配
+	syllables []string
+}
+
+// Suggester 基于前缀树的搜索建议，标题同时按原文、全拼与拼音首字母建立匹配键
+// 如 "Gin搭建博客" 可以用 gin搭、gindajian、gdjbk 以及从词中间开始的 博客、dajian、bk 等匹配
+// 前缀树的结果不足时再按音节匹配：从第一个字开始，每个字取拼音的前几个字母，中间的字可以跳过，
+// 如 gjbk（g-j-b-k，跳过 搭）、gindjbk、gjianbk
+type Suggester struct {
+	mu    sync.RWMutex
+	root  *trieNode
+	items map[string]*suggestItem
+}
+
+func NewSuggester() *Suggester {
+	return &Suggester{
+		root:  &trieNode{},
+		items: make(map[string]*suggestItem),
+	}
+}
+
+func suggestId(typ string, id uint) string {
+	return typ + ":" + strconv.Itoa(int(id))
+}
+
+// normalize 转换为小写，只保留文字与数字，查询与建立匹配键时使用相同规则
+func normalize(text string) string {
+	var buf strings.Builder
+	for _, r := range text {
+		if isWord(r) {
+			buf.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return buf.String()
+}
+
+// suggestKeys 生成匹配键，第一个词开始的键标记为从开头匹配
+func suggestKeys(text string) map[string]bool {
+	keys := make(map[string]bool)
+	add := func(key string, start bool) {
+		if runes := []rune(key); len(runes) > suggestKeyLen {
+			key = string(runes[:suggestKeyLen])
+		}
+		if key != "" {
+			keys[key] = keys[key] || start
+		}
+	}
+
+	// 原文：从每个汉字或单词开始
+	var units []string
+	for _, seg := range segments(text) {
+		if !isCJK(seg[0]) {
+			units = append(units, string(seg))
+			continue
+		}
+		for _, r := range seg {
+			units = append(units, string(r))
+		}
+	}
+	for i := 0; i < len(units) && i < suggestMaxStarts; i++ {
+		add(strings.Join(units[i:], ""), i == 0)
+	}
+
+	// 拼音：汉字按字拆分，从每个字或单词开始，分别生成全拼与首字母
+	words := syllables(text)
+	for i := 0; i < len(words) && i < suggestMaxStarts; i++ {
+		var full, initials strings.Builder
+		for _, w := range words[i:] {
+			full.WriteString(w)
+			initials.WriteRune([]rune(w)[0])
+		}
+		add(full.String(), i == 0)
+		add(initials.String(), i == 0)
+	}
+	return keys
+}
+
+// syllables 标题的拼音与单词，汉字按字拆分
+func syllables(text string) []string {
+	var words []string
+	for _, w := range strings.Split(slug.Make(text), "-") {
+		if w != "" {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// matchSyllables 查询能否依次由各音节的前缀拼成，第一个音节必须匹配，之后的音节可以跳过
+func matchSyllables(query string, words []string) bool {
+	if len(words) == 0 {
+		return false
+	}
+	// failed[wi][qi] 记录已确认无法匹配的位置，避免重复搜索
+	failed := make([][]bool, len(words))
+	for i := range failed {
+		failed[i] = make([]bool, len(query))
+	}
+	var match func(qi, wi int) bool
+	match = func(qi, wi int) bool {
+		if qi == len(query) {
+			return true
+		}
+		if wi == len(words) || failed[wi][qi] {
+			return false
+		}
+		w := words[wi]
+		for k := 1; k <= len(w) && qi+k <= len(query) && query[qi+k-1] == w[k-1]; k++ {
+			if match(qi+k, wi+1) {
+				return true
+			}
+		}
+		if wi > 0 && match(qi, wi+1) {
+			return true
+		}
+		failed[wi][qi] = true
+		return false
+	}
+	return match(0, 0)
+}
+
+// isAlnum 查询只包含小写字母与数字时才按音节匹配
+func isAlnum(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < 'a' || s[i] > 'z') && (s[i] < '0' || s[i] > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Put 新增或更新建议，weight 越大排序越靠前
+func (s *Suggester) Put(item Suggestion, weight int) {
+	id := suggestId(item.Type, item.Id)
+	keys := suggestKeys(item.Text)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(id)
+	it := &suggestItem{Suggestion: item, weight: weight, syllables: syllables(item.Text)}
+	for key, start := range keys {
+		node := s.root
+		for _, r := range key {
+			next, ok := node.children[r]
+			if !ok {
+				if node.children == nil {
+					node.children = make(map[rune]*trieNode)
+				}
+				next = &trieNode{}
+				node.children[r] = next
+			}
+			node = next
+		}
+		if node.items == nil {
+			node.items = make(map[string]bool)
+		}
+		node.items[id] = start
+		it.keys = append(it.keys, key)
+	}
+	s.items[id] = it
+}
+
+// Remove 删除建议，不存在时忽略
+func (s *Suggester) Remove(typ string, id uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(suggestId(typ, id))
+}
+
+// remove 调用方需持有写锁，删除后清理不再使用的节点
+func (s *Suggester) remove(id string) {
+	it, ok := s.items[id]
+	if !ok {
+		return
+	}
+	for _, key := range it.keys {
+		path := []*trieNode{s.root}
+		runes := []rune(key)
+		for _, r := range runes {
+			next := path[len(path)-1].children[r]
+			if next == nil {
+				break
+			}
+			path = append(path, next)
+		}
+		if len(path) != len(runes)+1 {
+			continue
+		}
+		delete(path[len(path)-1].items, id)
+		for i := len(path) - 1; i > 0; i-- {
+			node := path[i]
+			if len(node.items) > 0 || len(node.children) > 0 {
+				break
+			}
+			delete(path[i-1].children, runes[i-1])
+		}
+	}
+	delete(s.items, id)
+}
+
+// Suggest 按前缀查询建议
+// 从开头匹配的排在前面，其次是从词中间开始匹配、按音节匹配的，同类按权重排序
+func (s *Suggester) Suggest(prefix string, limit int) []Suggestion {
+	prefix = normalize(prefix)
+	if prefix == "" {
+		return []Suggestion{}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found := make(map[string]int)
+	node := s.root
+	for _, r := range prefix {
+		if node = node.children[r]; node == nil {
+			break
+		}
+	}
+
+	// 按层遍历，匹配键越短越先收集
+	var queue []*trieNode
+	if node != nil {
+		queue = append(queue, node)
+	}
+	for len(queue) > 0 && len(found) < suggestScan {
+		n := queue[0]
+		queue = queue[1:]
+		for id, start := range n.items {
+			match := matchMiddle
+			if start {
+				match = matchStart
+			}
+			if old, ok := found[id]; !ok || match < old {
+				found[id] = match
+			}
+		}
+		for _, child := range n.children {
+			queue = append(queue, child)
+		}
+	}
+
+	// 前缀树的结果不够时再逐个按音节匹配
+	if (limit <= 0 || len(found) < limit) && len(prefix) >= suggestMinFuzzy && isAlnum(prefix) {
+		for id, it := range s.items {
+			if len(found) >= suggestScan {
+				break
+			}
+			if _, ok := found[id]; !ok && matchSyllables(prefix, it.syllables) {
+				found[id] = matchSyllable
+			}
+		}
+	}
+
+	list := make([]*suggestItem, 0, len(found))
+	for id := range found {
+		list = append(list, s.items[id])
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if am, bm := found[suggestId(a.Type, a.Id)], found[suggestId(b.Type, b.Id)]; am != bm {
+			return am < bm
+		}
+		if a.weight != b.weight {
+			return a.weight > b.weight
+		}
+		return a.Text < b.Text
+	})
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	out := make([]Suggestion, len(list))
+	for i, it := range list {
+		out[i] = it.Suggestion
+	}
+	return out
+}
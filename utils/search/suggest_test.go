@@ -0,0 +1,63 @@
+package search
+
+import (
+	"testing"
+)
+
+func suggestTexts(list []Suggestion) []string {
+	texts := make([]string, len(list))
+	for i, s := range list {
+		texts[i] = s.Text
+	}
+	return texts
+}
+
+func TestSuggestPinyin(t *testing.T) {
+	s := NewSuggester()
+	s.Put(Suggestion{Type: "article", Id: 1, Text: "Gin搭建博客"}, 0)
+
+	tests := []struct {
+		name  string
+		query string
+		match bool
+	}{
+		{"原文前缀", "Gin搭", true},
+		{"全拼", "gindajian", true},
+		{"首字母", "gdjbk", true},
+		{"从词中间开始", "bk", true},
+		{"首字母跳过中间的字", "gjbk", true},
+		{"音节前缀", "gindjbk", true},
+		{"全拼与首字母混合", "gjianbk", true},
+		{"顺序不对", "gbj", false},
+		{"第一个字不匹配", "xjbk", false},
+		{"多出的字母", "gjbkx", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.Suggest(tt.query, 10)
+			if match := len(got) == 1; match != tt.match {
+				t.Errorf("Suggest(%q) = %v，应匹配: %v", tt.query, suggestTexts(got), tt.match)
+			}
+		})
+	}
+
+	s.Remove("article", 1)
+	if got := s.Suggest("gjbk", 10); len(got) != 0 {
+		t.Errorf("删除后仍能匹配: %v", suggestTexts(got))
+	}
+}
+
+// 前缀匹配排在音节匹配之前，不受权重影响
+func TestSuggestSyllableRank(t *testing.T) {
+	s := NewSuggester()
+	s.Put(Suggestion{Type: "article", Id: 1, Text: "Gin搭建博客"}, 100)
+	s.Put(Suggestion{Type: "article", Id: 2, Text: "Go教程"}, 1)
+
+	got := suggestTexts(s.Suggest("gj", 10))
+	if len(got) != 2 || got[0] != "Go教程" || got[1] != "Gin搭建博客" {
+		t.Errorf("Suggest(gj) = %v，应为 [Go教程 Gin搭建博客]", got)
+	}
+	if got = suggestTexts(s.Suggest("gj", 1)); len(got) != 1 || got[0] != "Go教程" {
+		t.Errorf("前缀树结果已足够时不应再按音节匹配: %v", got)
+	}
+}
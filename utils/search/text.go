@@ -0,0 +1,155 @@
+package search
+
+import (
+	"golang.org/x/net/html"
+	"html/template"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// blockTags 块级元素前后补换行，避免相邻段落的文字粘连
+var blockTags = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "td": true, "th": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"pre": true, "blockquote": true, "hr": true, "table": true, "ul": true, "ol": true,
+}
+
+// PlainText 去除 HTML 标签，得到用于索引与摘要的纯文本
+func PlainText(source string) string {
+	var buf strings.Builder
+	z := html.NewTokenizer(strings.NewReader(source))
+	skip := 0
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return strings.TrimSpace(collapseSpace(buf.String()))
+		case html.TextToken:
+			if skip == 0 {
+				buf.Write(z.Text())
+			}
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if tag == "script" || tag == "style" {
+				if tt == html.StartTagToken {
+					skip++
+				} else if skip > 0 {
+					skip--
+				}
+			}
+			if blockTags[tag] {
+				buf.WriteByte('\n')
+			}
+		}
+	}
+}
+
+// collapseSpace 合并连续空白，保留换行
+func collapseSpace(text string) string {
+	var buf strings.Builder
+	space, newline := false, false
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			space = true
+			newline = newline || r == '\n'
+			continue
+		}
+		if space && buf.Len() > 0 {
+			if newline {
+				buf.WriteByte('\n')
+			} else {
+				buf.WriteByte(' ')
+			}
+		}
+		space, newline = false, false
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// Highlight 用 <mark> 标出文本中的检索词，其余内容转义后返回
+// width 大于 0 时截取第一个检索词附近 width 个字符作为摘要
+func Highlight(text string, query string, width int) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// marks 记录每个命中词的 [起点, 终点)
+	var marks [][2]int
+	for _, term := range QueryTerms(query) {
+		t := []rune(term)
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) == term {
+				marks = append(marks, [2]int{i, i + len(t)})
+			}
+		}
+	}
+	sort.Slice(marks, func(i, j int) bool { return marks[i][0] < marks[j][0] })
+	// 合并重叠或相邻的命中，如中文的相邻两字切分
+	var merged [][2]int
+	for _, m := range marks {
+		if n := len(merged); n > 0 && m[0] <= merged[n-1][1] {
+			if m[1] > merged[n-1][1] {
+				merged[n-1][1] = m[1]
+			}
+			continue
+		}
+		merged = append(merged, m)
+	}
+
+	start, end := 0, len(runes)
+	if width > 0 && len(runes) > width {
+		if len(merged) > 0 {
+			// 命中词前保留少量上下文
+			start = merged[0][0] - width/5
+			if start < 0 {
+				start = 0
+			}
+		}
+		end = start + width
+		if end > len(runes) {
+			end = len(runes)
+			start = end - width
+		}
+	}
+
+	var buf strings.Builder
+	if start > 0 {
+		buf.WriteString("…")
+	}
+	pos := start
+	for _, m := range merged {
+		if m[1] <= start || m[0] >= end {
+			continue
+		}
+		from, to := max(m[0], start), min(m[1], end)
+		buf.WriteString(template.HTMLEscapeString(string(runes[pos:from])))
+		buf.WriteString("<mark>")
+		buf.WriteString(template.HTMLEscapeString(string(runes[from:to])))
+		buf.WriteString("</mark>")
+		pos = to
+	}
+	buf.WriteString(template.HTMLEscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		buf.WriteString("…")
+	}
+	return buf.String()
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
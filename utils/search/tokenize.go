@@ -0,0 +1,90 @@
+package search
+
+import (
+	"unicode"
+)
+
+// isCJK 中日韩文字没有空格分词，按相邻两字切分
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+func isWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// segments 将文本拆分为连续的中文片段与单词，统一转换为小写
+// 如 "使用Gin搭建博客" 拆分为 使用、gin、搭建博客
+func segments(text string) [][]rune {
+	var out [][]rune
+	var cur []rune
+	cjk := false
+	flush := func() {
+		if len(cur) > 0 {
+			out = append(out, cur)
+			cur = nil
+		}
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			if !cjk {
+				flush()
+			}
+			cjk = true
+		case isWord(r):
+			if cjk {
+				flush()
+			}
+			cjk = false
+			r = unicode.ToLower(r)
+		default:
+			flush()
+			continue
+		}
+		cur = append(cur, r)
+	}
+	flush()
+	return out
+}
+
+// Tokenize 生成索引用的词，中文片段同时输出单字与相邻两字，兼顾单字搜索与排序
+func Tokenize(text string) []string {
+	var tokens []string
+	for _, seg := range segments(text) {
+		if !isCJK(seg[0]) {
+			tokens = append(tokens, string(seg))
+			continue
+		}
+		for i := range seg {
+			tokens = append(tokens, string(seg[i]))
+			if i+1 < len(seg) {
+				tokens = append(tokens, string(seg[i:i+2]))
+			}
+		}
+	}
+	return tokens
+}
+
+// QueryTerms 生成检索用的词，去重后返回
+// 中文片段只取相邻两字，单个汉字时取单字，文档需包含全部词才算命中
+func QueryTerms(text string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	add := func(term string) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	for _, seg := range segments(text) {
+		if !isCJK(seg[0]) || len(seg) == 1 {
+			add(string(seg))
+			continue
+		}
+		for i := 0; i+1 < len(seg); i++ {
+			add(string(seg[i : i+2]))
+		}
+	}
+	return terms
+}
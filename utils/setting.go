@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"gopkg.in/ini.v1"
+	"time"
 )
 
 var (
@@ -10,6 +11,16 @@ var (
 	HttpPort string
 	JwtKey   string
 
+	AccessTokenExpire  time.Duration
+	RefreshTokenExpire time.Duration
+	ResetTokenExpire   time.Duration
+	SiteUrl            string
+
+	JwtAlg      string
+	JwtKeyDir   string
+	JwtRotate   time.Duration
+	JwtKeyGrace time.Duration
+
 	DbHost     string
 	DbPort     string
 	DbUser     string
@@ -23,18 +34,98 @@ var (
 	QiniuSever string
 
 	LogFilePath string
+
+	MailDriver   string
+	MailHost     string
+	MailPort     int
+	MailUsername string
+	MailPassword string
+	MailFrom     string
+	MailFileDir  string
+
+	StoreType     string
+	RedisAddr     string
+	RedisPassword string
+	RedisDb       int
+
+	LoginFailWindow   time.Duration
+	LoginFreeAttempts int
+	LoginMaxAttempts  int
+	LoginIpFreeTries  int
+	LoginIpMaxTries   int
+	LoginBackoffBase  time.Duration
+	LoginBackoffMax   time.Duration
+	LoginLockDuration time.Duration
+
+	EmailVerifyExpire    time.Duration
+	VerifyResendInterval time.Duration
+	VerifyResendDaily    int
+
+	CaptchaType          string
+	CaptchaExpire        time.Duration
+	CaptchaPowDifficulty int
+	CaptchaRoutes        []string
+
+	RevisionMaxCount int
+	RevisionMaxAge   time.Duration
+
+	SanitizeCommentTags   []string
+	SanitizeArticleTags   []string
+	SanitizeArticleStyles []string
+	SanitizeIframeHosts   []string
+
+	SearchEngine string
+
+	CommentMaxDepth  int
+	CommentAvatarUrl string
+
+	SpamBlocklist    string
+	SpamRateLimit    int
+	SpamRateWindow   time.Duration
+	SpamMaxLinks     int
+	SpamRepeatWindow time.Duration
+	SpamMinSamples   int
+	SpamApproveBelow float64
+	SpamHoldAbove    float64
+	SpamRejectAbove  float64
+
+	OAuthRedirectFront string
+	OAuthProviders     = map[string]OAuthProvider{}
 )
 
+// OAuthProvider 第三方登录配置，ClientId 为空表示未启用
+type OAuthProvider struct {
+	ClientId     string
+	ClientSecret string
+	RedirectUrl  string
+	// Issuer 仅通用 OIDC 使用，用于服务发现
+	Issuer string
+	Scopes []string
+}
+
 // 初始化
 func init() {
 	file, err := ini.Load("config/config.ini")
 	if err != nil {
 		fmt.Println("配置文件读取错误，请检查文件路径:", err)
+		// 读取失败时全部使用默认值，如在各包目录下运行测试
+		file = ini.Empty()
 	}
 	LoadServer(file)
+	LoadJwt(file)
 	LoadData(file)
 	LoadQiniu(file)
 	LoadLog(file)
+	LoadMail(file)
+	LoadStore(file)
+	LoadSecurity(file)
+	LoadOAuth(file)
+	LoadCaptcha(file)
+	LoadRevision(file)
+	LoadSanitize(file)
+	LoadSearch(file)
+	LoadComment(file)
+	LoadSpam(file)
 }
 
 func LoadLog(file *ini.File) {
@@ -45,6 +136,17 @@ func LoadServer(file *ini.File) {
 	AppMode = file.Section("server").Key("AppMode").MustString("debug")
 	HttpPort = file.Section("server").Key("HttpPort").MustString(":3000")
 	JwtKey = file.Section("server").Key("JwtKey").MustString("89js82js72")
+	AccessTokenExpire = file.Section("server").Key("AccessTokenExpire").MustDuration(15 * time.Minute)
+	RefreshTokenExpire = file.Section("server").Key("RefreshTokenExpire").MustDuration(7 * 24 * time.Hour)
+	ResetTokenExpire = file.Section("server").Key("ResetTokenExpire").MustDuration(30 * time.Minute)
+	SiteUrl = file.Section("server").Key("SiteUrl").MustString("http://localhost:3000")
+}
+
+func LoadJwt(file *ini.File) {
+	JwtAlg = file.Section("jwt").Key("Alg").In("HS256", []string{"HS256", "RS256", "EdDSA"})
+	JwtKeyDir = file.Section("jwt").Key("KeyDir").MustString("config/keys")
+	JwtRotate = file.Section("jwt").Key("RotateInterval").MustDuration(30 * 24 * time.Hour)
+	JwtKeyGrace = file.Section("jwt").Key("KeyGrace").MustDuration(24 * time.Hour)
 }
 
 func LoadData(file *ini.File) {
@@ -62,3 +164,99 @@ func LoadQiniu(file *ini.File) {
 	Bucket = file.Section("qiniu").Key("Bucket").String()
 	QiniuSever = file.Section("qiniu").Key("QiniuSever").String()
 }
+
+func LoadMail(file *ini.File) {
+	MailDriver = file.Section("mail").Key("Driver").MustString("file")
+	MailHost = file.Section("mail").Key("Host").MustString("localhost")
+	MailPort = file.Section("mail").Key("Port").MustInt(25)
+	MailUsername = file.Section("mail").Key("Username").String()
+	MailPassword = file.Section("mail").Key("Password").String()
+	MailFrom = file.Section("mail").Key("From").MustString("GinBlog <noreply@localhost>")
+	MailFileDir = file.Section("mail").Key("FileDir").MustString("log/mail")
+}
+
+func LoadStore(file *ini.File) {
+	StoreType = file.Section("store").Key("Type").MustString("memory")
+	RedisAddr = file.Section("store").Key("RedisAddr").MustString("127.0.0.1:6379")
+	RedisPassword = file.Section("store").Key("RedisPassword").String()
+	RedisDb = file.Section("store").Key("RedisDb").MustInt(0)
+}
+
+func LoadSecurity(file *ini.File) {
+	LoginFailWindow = file.Section("security").Key("LoginFailWindow").MustDuration(time.Hour)
+	LoginFreeAttempts = file.Section("security").Key("LoginFreeAttempts").MustInt(3)
+	LoginMaxAttempts = file.Section("security").Key("LoginMaxAttempts").MustInt(10)
+	LoginIpFreeTries = file.Section("security").Key("LoginIpFreeTries").MustInt(10)
+	LoginIpMaxTries = file.Section("security").Key("LoginIpMaxTries").MustInt(50)
+	LoginBackoffBase = file.Section("security").Key("LoginBackoffBase").MustDuration(time.Second)
+	LoginBackoffMax = file.Section("security").Key("LoginBackoffMax").MustDuration(5 * time.Minute)
+	LoginLockDuration = file.Section("security").Key("LoginLockDuration").MustDuration(30 * time.Minute)
+	EmailVerifyExpire = file.Section("security").Key("EmailVerifyExpire").MustDuration(24 * time.Hour)
+	VerifyResendInterval = file.Section("security").Key("VerifyResendInterval").MustDuration(time.Minute)
+	VerifyResendDaily = file.Section("security").Key("VerifyResendDaily").MustInt(5)
+}
+
+func LoadOAuth(file *ini.File) {
+	OAuthRedirectFront = file.Section("oauth").Key("RedirectFront").String()
+	for _, name := range []string{"github", "gitee", "oidc"} {
+		section := file.Section("oauth." + name)
+		if section.Key("ClientId").String() == "" {
+			continue
+		}
+		OAuthProviders[name] = OAuthProvider{
+			ClientId:     section.Key("ClientId").String(),
+			ClientSecret: section.Key("ClientSecret").String(),
+			RedirectUrl:  section.Key("RedirectUrl").MustString(SiteUrl + "/api/v1/oauth/" + name + "/callback"),
+			Issuer:       section.Key("Issuer").String(),
+			Scopes:       section.Key("Scopes").Strings(","),
+		}
+	}
+}
+
+func LoadCaptcha(file *ini.File) {
+	CaptchaType = file.Section("captcha").Key("Type").In("image", []string{"image", "pow"})
+	CaptchaExpire = file.Section("captcha").Key("Expire").MustDuration(5 * time.Minute)
+	CaptchaPowDifficulty = file.Section("captcha").Key("PowDifficulty").MustInt(18)
+	CaptchaRoutes = file.Section("captcha").Key("Routes").Strings(",")
+}
+
+func LoadRevision(file *ini.File) {
+	RevisionMaxCount = file.Section("revision").Key("MaxCount").MustInt(50)
+	RevisionMaxAge = file.Section("revision").Key("MaxAge").MustDuration(0)
+}
+
+// LoadSanitize 未配置时使用默认白名单，配置为空表示不允许任何标签
+func LoadSanitize(file *ini.File) {
+	section := file.Section("sanitize")
+	list := func(key string, def []string) []string {
+		if !section.HasKey(key) {
+			return def
+		}
+		return section.Key(key).Strings(",")
+	}
+	SanitizeCommentTags = list("CommentTags", []string{"p", "br", "b", "strong", "i", "em", "del", "code", "pre", "blockquote", "a"})
+	SanitizeArticleTags = list("ArticleTags", nil)
+	SanitizeArticleStyles = list("ArticleStyles", []string{"text-align", "color", "background-color", "text-decoration", "padding-left", "font-size", "width", "height"})
+	SanitizeIframeHosts = list("IframeHosts", []string{"player.bilibili.com", "www.youtube.com"})
+}
+
+func LoadSearch(file *ini.File) {
+	SearchEngine = file.Section("search").Key("Engine").In("memory", []string{"memory", "mysql"})
+}
+
+func LoadComment(file *ini.File) {
+	CommentMaxDepth = file.Section("comment").Key("MaxDepth").RangeInt(3, 1, 10)
+	CommentAvatarUrl = file.Section("comment").Key("AvatarUrl").MustString("https://www.gravatar.com/avatar/")
+}
+
+func LoadSpam(file *ini.File) {
+	SpamBlocklist = file.Section("spam").Key("Blocklist").MustString("config/spam_blocklist.txt")
+	SpamRateLimit = file.Section("spam").Key("RateLimit").MustInt(5)
+	SpamRateWindow = file.Section("spam").Key("RateWindow").MustDuration(10 * time.Minute)
+	SpamMaxLinks = file.Section("spam").Key("MaxLinks").MustInt(2)
+	SpamRepeatWindow = file.Section("spam").Key("RepeatWindow").MustDuration(24 * time.Hour)
+	SpamMinSamples = file.Section("spam").Key("MinSamples").MustInt(20)
+	SpamApproveBelow = file.Section("spam").Key("ApproveBelow").MustFloat64(0.1)
+	SpamHoldAbove = file.Section("spam").Key("HoldAbove").MustFloat64(0.5)
+	SpamRejectAbove = file.Section("spam").Key("RejectAbove").MustFloat64(0.9)
+}
@@ -0,0 +1,46 @@
+package slug
+
+import (
+	"github.com/mozillazg/go-pinyin"
+	"strings"
+	"unicode"
+)
+
+// MaxLen 生成的 slug 最大长度（按字符计）
+const MaxLen = 60
+
+var pinyinArgs = pinyin.NewArgs()
+
+// Make 将标题或名称转换为适合放在 URL 中的 slug
+// 汉字转换为不带声调的拼音，每个字之间用 "-" 分隔；字母统一小写，
+// 字母和数字以外的字符都视为分隔符，连续分隔符合并为一个 "-"
+func Make(s string) string {
+	var words []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			if py := pinyin.SinglePinyin(r, pinyinArgs); len(py) > 0 {
+				words = append(words, py[0])
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	out := strings.Join(words, "-")
+	if runes := []rune(out); len(runes) > MaxLen {
+		out = strings.TrimRight(string(runes[:MaxLen]), "-")
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package spam
+
+import (
+	"github.com/wejectchen/ginblog/utils/search"
+	"math"
+	"sort"
+)
+
+// 参与打分的特征词数量，只取区分度最高的若干个词，避免长评论的概率被大量普通词推向极端
+// 训练中出现过的词少于 minTokens 个时依据不足，按 0.5 处理
+const (
+	topTokens = 20
+	minTokens = 3
+)
+
+// Classifier 朴素贝叶斯分类器，按词统计在正常评论与垃圾评论中出现的评论数
+// 训练完成后只读，可并发调用 Score；重新训练时应创建新的分类器
+type Classifier struct {
+	// counts 每个词出现在多少条正常评论 [0] 与垃圾评论 [1] 中
+	counts map[string]*[2]int
+	docs   [2]int
+}
+
+func NewClassifier() *Classifier {
+	return &Classifier{counts: make(map[string]*[2]int)}
+}
+
+// tokens 评论中的词，同一条评论中重复出现的词只计一次
+func tokens(text string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, token := range search.Tokenize(text) {
+		if !seen[token] {
+			seen[token] = true
+			out = append(out, token)
+		}
+	}
+	return out
+}
+
+// Train 学习一条已人工审核的评论，spam 表示垃圾评论
+func (c *Classifier) Train(text string, spam bool) {
+	class := 0
+	if spam {
+		class = 1
+	}
+	c.docs[class]++
+	for _, token := range tokens(text) {
+		n, ok := c.counts[token]
+		if !ok {
+			n = &[2]int{}
+			c.counts[token] = n
+		}
+		n[class]++
+	}
+}
+
+// Docs 参与训练的正常评论与垃圾评论数量
+func (c *Classifier) Docs() (ham int, spam int) {
+	return c.docs[0], c.docs[1]
+}
+
+// Score 评论为垃圾评论的概率（0-1），两类先验概率按相等处理
+// 训练中没有出现过的词不参与计算，可用的词太少时返回 0.5
+func (c *Classifier) Score(text string) float64 {
+	if c.docs[0] == 0 || c.docs[1] == 0 {
+		return 0.5
+	}
+	var ratios []float64
+	for _, token := range tokens(text) {
+		n, ok := c.counts[token]
+		if !ok {
+			continue
+		}
+		// 拉普拉斯平滑，避免只在一类中出现的词得到无穷大的权重
+		pHam := float64(n[0]+1) / float64(c.docs[0]+2)
+		pSpam := float64(n[1]+1) / float64(c.docs[1]+2)
+		ratios = append(ratios, math.Log(pSpam/pHam))
+	}
+	if len(ratios) < minTokens {
+		return 0.5
+	}
+	sort.Slice(ratios, func(i, j int) bool { return math.Abs(ratios[i]) > math.Abs(ratios[j]) })
+	if len(ratios) > topTokens {
+		ratios = ratios[:topTokens]
+	}
+	sum := 0.0
+	for _, r := range ratios {
+		sum += r
+	}
+	return 1 / (1 + math.Exp(-sum))
+}
@@ -0,0 +1,75 @@
+package spam
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Blocklist 关键词与正则黑名单，命中任意一条即视为垃圾评论
+type Blocklist struct {
+	words    []string
+	patterns []*regexp.Regexp
+}
+
+// LoadBlocklist 读取黑名单文件，每行一条规则，re: 开头为正则表达式，# 开头为注释
+// 关键词不区分大小写；文件不存在时返回空黑名单
+func LoadBlocklist(path string) (*Blocklist, error) {
+	b := &Blocklist{}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return b, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "re:"):
+			re, err := regexp.Compile("(?i)" + strings.TrimSpace(strings.TrimPrefix(line, "re:")))
+			if err != nil {
+				return b, err
+			}
+			b.patterns = append(b.patterns, re)
+		default:
+			b.words = append(b.words, strings.ToLower(line))
+		}
+	}
+	return b, scanner.Err()
+}
+
+// Match 返回第一条命中的规则，未命中时返回空字符串
+func (b *Blocklist) Match(texts ...string) string {
+	for _, text := range texts {
+		lower := strings.ToLower(text)
+		for _, word := range b.words {
+			if strings.Contains(lower, word) {
+				return word
+			}
+		}
+		for _, re := range b.patterns {
+			if re.MatchString(text) {
+				return "re:" + re.String()[len("(?i)"):]
+			}
+		}
+	}
+	return ""
+}
+
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)[^\s"'<>]+`)
+
+// CountLinks 统计评论中不同链接的数量，链接地址与显示文字相同时只计一次
+func CountLinks(html string) int {
+	seen := make(map[string]bool)
+	for _, link := range linkPattern.FindAllString(html, -1) {
+		link = strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(link), "http://"), "https://")
+		seen[strings.TrimRight(link, "/")] = true
+	}
+	return len(seen)
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+type memoryItem struct {
+	value    string
+	expireAt time.Time
+}
+
+func (i memoryItem) expired(now time.Time) bool {
+	return !i.expireAt.IsZero() && now.After(i.expireAt)
+}
+
+// MemoryStore 进程内存储，只适用于单实例部署
+type MemoryStore struct {
+	mu    sync.Mutex
+	items map[string]memoryItem
+}
+
+// NewMemoryStore 创建内存存储，并定期清理过期的键
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{items: make(map[string]memoryItem)}
+	go func() {
+		for range time.Tick(time.Minute) {
+			s.gc()
+		}
+	}()
+	return s
+}
+
+func (s *MemoryStore) gc() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range s.items {
+		if v.expired(now) {
+			delete(s.items, k)
+		}
+	}
+}
+
+// get 调用方需持有锁
+func (s *MemoryStore) get(key string) (memoryItem, bool) {
+	item, ok := s.items[key]
+	if !ok {
+		return item, false
+	}
+	if item.expired(time.Now()) {
+		delete(s.items, key)
+		return item, false
+	}
+	return item, true
+}
+
+func expireAt(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+func (s *MemoryStore) Incr(key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.get(key)
+	if !ok {
+		item = memoryItem{value: "0", expireAt: expireAt(ttl)}
+	}
+	n, err := strconv.ParseInt(item.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	n++
+	item.value = strconv.FormatInt(n, 10)
+	s.items[key] = item
+	return n, nil
+}
+
+func (s *MemoryStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, _ := s.get(key)
+	return item.value, nil
+}
+
+func (s *MemoryStore) Set(key string, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[key] = memoryItem{value: value, expireAt: expireAt(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Take(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, _ := s.get(key)
+	delete(s.items, key)
+	return item.value, nil
+}
+
+func (s *MemoryStore) Del(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, key)
+	return nil
+}
+
+func (s *MemoryStore) TTL(key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.get(key)
+	if !ok || item.expireAt.IsZero() {
+		return 0, nil
+	}
+	return time.Until(item.expireAt), nil
+}
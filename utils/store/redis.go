@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// RedisStore 基于 Redis（或兼容协议的服务）的存储，可在多实例间共享
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore 连接 Redis
+func NewRedisStore(addr string, password string, db int) (*RedisStore, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, err
+	}
+	return &RedisStore{client: client}, nil
+}
+
+func (s *RedisStore) Incr(key string, ttl time.Duration) (int64, error) {
+	ctx := context.Background()
+	n, err := s.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if n == 1 && ttl > 0 {
+		err = s.client.Expire(ctx, key, ttl).Err()
+	}
+	return n, err
+}
+
+func (s *RedisStore) Get(key string) (string, error) {
+	value, err := s.client.Get(context.Background(), key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return value, err
+}
+
+func (s *RedisStore) Set(key string, value string, ttl time.Duration) error {
+	return s.client.Set(context.Background(), key, value, ttl).Err()
+}
+
+func (s *RedisStore) Take(key string) (string, error) {
+	ctx := context.Background()
+	var get *redis.StringCmd
+	// MULTI/EXEC 保证读取和删除之间不会被其他请求插入
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pipe.Del(ctx, key)
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return get.Val(), nil
+}
+
+func (s *RedisStore) Del(key string) error {
+	return s.client.Del(context.Background(), key).Err()
+}
+
+func (s *RedisStore) TTL(key string) (time.Duration, error) {
+	ttl, err := s.client.TTL(context.Background(), key).Result()
+	if err != nil || ttl < 0 {
+		return 0, err
+	}
+	return ttl, nil
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"fmt"
+	"github.com/wejectchen/ginblog/utils"
+	"os"
+	"time"
+)
+
+// Store 带过期时间的键值/计数存储
+// 默认使用进程内存，多实例部署时可切换到 Redis 以共享状态
+type Store interface {
+	// Incr 计数加一并返回新值，计数器新建时设置过期时间
+	Incr(key string, ttl time.Duration) (int64, error)
+	// Get 读取值，不存在时返回空字符串
+	Get(key string) (string, error)
+	// Set 写入值，ttl 为 0 表示不过期
+	Set(key string, value string, ttl time.Duration) error
+	// Take 读取并删除，用于一次性凭证
+	Take(key string) (string, error)
+	// Del 删除
+	Del(key string) error
+	// TTL 剩余有效期，不存在或未设置过期时返回 0
+	TTL(key string) (time.Duration, error)
+}
+
+// Default 当前使用的存储，由配置 [store] Type 决定
+var Default Store
+
+func init() {
+	switch utils.StoreType {
+	case "redis":
+		s, err := NewRedisStore(utils.RedisAddr, utils.RedisPassword, utils.RedisDb)
+		if err != nil {
+			fmt.Println("连接Redis失败，请检查参数：", err)
+			os.Exit(1)
+		}
+		Default = s
+	default:
+		Default = NewMemoryStore()
+	}
+}
@@ -0,0 +1,13 @@
+// 文章状态，与后端 model.ArtDraft 等常量保持一致
+export const statusList = [
+  { value: 1, label: '草稿' },
+  { value: 2, label: '待审核' },
+  { value: 3, label: '已发布' },
+  { value: 4, label: '定时发布' },
+  { value: 5, label: '已归档' },
+]
+
+export function statusLabel(status) {
+  const item = statusList.find((s) => s.value === status)
+  return item ? item.label : '未知'
+}
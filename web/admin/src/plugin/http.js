@@ -1,5 +1,6 @@
 import Vue from 'vue'
 import axios from 'axios'
+import router from '../router'
 
 let Url = 'http://localhost:3000/api/v1/'
 
@@ -10,6 +11,40 @@ axios.interceptors.request.use(config => {
   return config
 })
 
+// 同时过期的多个请求共用一次刷新，避免刷新令牌被重复使用而注销整个登录
+let refreshing = null
+
+function refreshToken() {
+  if (!refreshing) {
+    refreshing = axios
+      .post('token/refresh', { refresh_token: window.sessionStorage.getItem('refresh_token') }, { _retry: true })
+      .then(({ data: res }) => {
+        if (res.status !== 200) throw new Error(res.message)
+        window.sessionStorage.setItem('token', res.token)
+        window.sessionStorage.setItem('refresh_token', res.refresh_token)
+      })
+      .finally(() => {
+        refreshing = null
+      })
+  }
+  return refreshing
+}
+
+// 访问令牌过期（1005）时用刷新令牌换取新令牌后重试，刷新失败则重新登录
+axios.interceptors.response.use(async response => {
+  const config = response.config
+  if (response.data.status !== 1005 || config._retry) return response
+  try {
+    await refreshToken()
+  } catch (err) {
+    window.sessionStorage.clear()
+    router.push('/login').catch(err => err)
+    return response
+  }
+  config._retry = true
+  return axios(config)
+})
+
 Vue.prototype.$http = axios
 
 export { Url }
@@ -8,6 +8,7 @@ const Index = () => import(/* webpackChunkName: "Index" */ '../components/admin/
 const AddArt = () => import(/* webpackChunkName: "AddArt" */ '../components/article/AddArt.vue')
 const ArtList = () => import(/* webpackChunkName: "ArtList" */ '../components/article/ArtList.vue')
 const CateList = () => import(/* webpackChunkName: "CateList" */ '../components/category/CateList.vue')
+const TagList = () => import(/* webpackChunkName: "TagList" */ '../components/tag/TagList.vue')
 const UserList = () => import(/* webpackChunkName: "UserList" */ '../components/user/UserList.vue')
 const Profile = () => import(/* webpackChunkName: "UserList" */ '../components/user/Profile.vue')
 const CommentList = () => import(/* webpackChunkName: "UserList" */ '../components/comment/commentList.vue')
@@ -74,6 +75,13 @@ const routes = [
           title: '分类列表'
         }
       },
+      {
+        path: 'taglist',
+        component: TagList,
+        meta: {
+          title: '标签列表'
+        }
+      },
       {
         path: 'userlist',
         component: UserList,
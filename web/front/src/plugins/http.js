@@ -4,4 +4,48 @@ import axios from 'axios'
 // axios请求地址
 axios.defaults.baseURL = 'http://localhost:3000/api/v1'
 
+axios.interceptors.request.use(config => {
+  const token = window.sessionStorage.getItem('token')
+  if (token) {
+    config.headers.Authorization = `Bearer ${token}`
+  }
+  return config
+})
+
+// 同时过期的多个请求共用一次刷新，避免刷新令牌被重复使用而注销整个登录
+let refreshing = null
+
+function refreshToken() {
+  if (!refreshing) {
+    refreshing = axios
+      .post('token/refresh', { refresh_token: window.sessionStorage.getItem('refresh_token') }, { _retry: true })
+      .then(({ data: res }) => {
+        if (res.status !== 200) throw new Error(res.message)
+        window.sessionStorage.setItem('token', res.token)
+        window.sessionStorage.setItem('refresh_token', res.refresh_token)
+      })
+      .finally(() => {
+        refreshing = null
+      })
+  }
+  return refreshing
+}
+
+// 访问令牌过期（1005）时用刷新令牌换取新令牌后重试；
+// 刷新失败或令牌已被吊销（1009）时丢弃本地令牌，以游客身份重试，游客可用的接口（如评论）不受影响
+axios.interceptors.response.use(async response => {
+  const config = response.config
+  const status = response.data.status
+  if ((status !== 1005 && status !== 1009) || config._retry) return response
+  config._retry = true
+  try {
+    if (status === 1009) throw new Error(response.data.message)
+    await refreshToken()
+  } catch (err) {
+    window.sessionStorage.clear()
+    delete config.headers.Authorization
+  }
+  return axios(config)
+})
+
 Vue.prototype.$http = axios
@@ -7,8 +7,14 @@ const Detail = () =>
   import(/* webpackChunkName: "group-detail" */ '../components/Details.vue')
 const Category = () =>
   import(/* webpackChunkName: "group-category" */ '../components/CateList.vue')
+const Tag = () =>
+  import(/* webpackChunkName: "group-tag" */ '../components/TagList.vue')
 const Search = () =>
   import(/* webpackChunkName: "group-search" */ '../components/Search.vue')
+const ResetPassword = () =>
+  import(/* webpackChunkName: "group-account" */ '../components/ResetPassword.vue')
+const VerifyEmail = () =>
+  import(/* webpackChunkName: "group-account" */ '../components/VerifyEmail.vue')
 
 Vue.use(VueRouter)
 
@@ -33,11 +39,27 @@ const routes = [
     meta: { title: '分类信息' },
     props: true
   },
+  {
+    path: '/tag/:slug',
+    component: Tag,
+    meta: { title: '标签' },
+    props: true
+  },
   {
     path: '/search/:title',
     component: Search,
     meta: { title: '搜索结果' },
     props: true
+  },
+  {
+    path: '/resetpassword',
+    component: ResetPassword,
+    meta: { title: '重置密码' }
+  },
+  {
+    path: '/verifyemail',
+    component: VerifyEmail,
+    meta: { title: '验证邮箱' }
   }
 ]
 